@@ -2,59 +2,418 @@ package containuum
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/client"
 )
 
+var (
+	// ErrAlreadyStarted is returned by Monitor.Start if the monitor has already been started.
+	ErrAlreadyStarted = errors.New("monitor already started")
+
+	// ErrNotRunning is returned by Monitor.RefreshNow if the monitor is not running.
+	ErrNotRunning = errors.New("monitor is not running")
+
+	// ErrConnectTimeout is returned (wrapped) if the initial connection to Docker
+	// does not complete within the time set by WithConnectTimeout.
+	ErrConnectTimeout = errors.New("timed out connecting to docker")
+
+	// ErrInvalidOption is returned (wrapped) by Run, Monitor.Start and
+	// DiffAgainstFile if the options given are invalid or contradictory.
+	ErrInvalidOption = errors.New("invalid option")
+
+	// ErrStartupTimeout is returned (wrapped) if the initial set of containers
+	// is not retrieved within the time set by WithStartupTimeout.
+	ErrStartupTimeout = errors.New("timed out starting monitor")
+
+	// ErrMaxRetriesExceeded is returned (wrapped, along with the error that caused
+	// the final disconnection) if the maximum number of reconnection attempts
+	// configured with WithAutoReconnect is exceeded.
+	ErrMaxRetriesExceeded = errors.New("max reconnect retries exceeded")
+
+	// ErrTooManyContainers is returned (wrapped) when refreshing the containers
+	// if more are listed than allowed by WithMaxContainers.
+	ErrTooManyContainers = errors.New("too many containers")
+)
+
 // Run monitors Docker containers and calls the callback when the filtered set changes.
 // It emits the initial state immediately, then watches for changes.
 // Blocks until the context is cancelled or an error occurs.
 func Run(ctx context.Context, callback Callback, opts ...Option) error {
+	return New(callback, opts...).Start(ctx)
+}
+
+// Monitor is a handle to a container monitor, allowing it to be stopped
+// gracefully. Use New to create one.
+type Monitor struct {
+	callback Callback
+	cfg      *config
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stop    chan struct{}
+	refresh chan chan error
+
+	lastEvent    atomic.Int64
+	lastCallback atomic.Int64
+	count        atomic.Int64
+	filter       atomic.Pointer[Filter]
+}
+
+// New creates a Monitor that will call the callback when the filtered set of
+// containers changes. The monitor does nothing until Start is called.
+func New(callback Callback, opts ...Option) *Monitor {
 	cfg := defaultConfig()
 	for _, opt := range opts {
 		opt(cfg)
 	}
 
-	dockerClient := cfg.client
-	if dockerClient == nil {
-		var cleanup func() error
-		var err error
-		dockerClient, cleanup, err = newDefaultClient()
-		if err != nil {
-			return err
+	m := &Monitor{
+		callback: callback,
+		cfg:      cfg,
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		refresh:  make(chan chan error),
+	}
+	if cfg.filter != nil {
+		m.filter.Store(&cfg.filter)
+	}
+	return m
+}
+
+// Start begins monitoring. It emits the initial state immediately, then watches
+// for changes. Blocks until the context is cancelled, Stop is called, or an error
+// occurs. If the context is cancelled, its error is returned; if the monitor is
+// stopped with Stop, nil is returned. If the options are invalid, an error
+// wrapping ErrInvalidOption is returned immediately.
+//
+// A Monitor may only be started once.
+func (m *Monitor) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return ErrAlreadyStarted
+	}
+	m.started = true
+	if m.stopped {
+		m.mu.Unlock()
+		close(m.done)
+		return nil
+	}
+	if err := m.cfg.validate(); err != nil {
+		m.mu.Unlock()
+		close(m.done)
+		return err
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+	m.mu.Unlock()
+
+	defer close(m.done)
+	defer cancel()
+
+	err := m.run(runCtx)
+	if ctx.Err() == nil && errors.Is(err, context.Canceled) {
+		m.mu.Lock()
+		stopped := m.stopped
+		m.mu.Unlock()
+		if stopped {
+			return nil
 		}
-		defer func() { _ = cleanup() }()
 	}
+	return err
+}
+
+// Stop stops the monitor, and blocks until Start has returned. Any in-flight
+// refresh is allowed to finish, including invoking the callback, but no further
+// refreshes are started. Stop may be called multiple times, and before or after
+// Start. It must not be called from within a callback.
+//
+// If the given context is done before the monitor has finished, the in-flight
+// refresh is abandoned as if the context passed to Start had been cancelled,
+// and the context's error is returned once Start has returned. A callback that
+// is already executing is always allowed to finish. Either way, Start returns
+// nil rather than an error.
+func (m *Monitor) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.stopped {
+		m.stopped = true
+		close(m.stop)
+	}
+	started := m.started
+	cancel := m.cancel
+	m.mu.Unlock()
+
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	if cancel != nil {
+		cancel()
+	}
+	<-m.done
+	return ctx.Err()
+}
 
+// RefreshNow immediately refreshes the containers, invoking the callback if
+// they have changed. This is useful if something has changed that Docker will
+// not send an event for. Blocks until the refresh has completed, and returns
+// any error encountered. If the monitor is reconnecting to Docker, the refresh
+// will occur once it has reconnected.
+//
+// Returns ErrNotRunning if the monitor has not been started or has stopped.
+// It must not be called from within a callback.
+func (m *Monitor) RefreshNow() error {
+	m.mu.Lock()
+	started := m.started
+	m.mu.Unlock()
+
+	if !started {
+		return ErrNotRunning
+	}
+
+	result := make(chan error, 1)
+	select {
+	case m.refresh <- result:
+	case <-m.done:
+		return ErrNotRunning
+	}
+
+	return <-result
+}
+
+// SetFilter replaces the filter set with WithFilter, or removes it if filter is
+// nil. If the monitor is running, the containers are then refreshed as with
+// RefreshNow, so that the callback reflects the new filter straight away, and
+// any error from the refresh is returned.
+//
+// It must not be called from within a callback.
+func (m *Monitor) SetFilter(filter Filter) error {
+	if filter == nil {
+		m.filter.Store(nil)
+	} else {
+		m.filter.Store(&filter)
+	}
+
+	if err := m.RefreshNow(); !errors.Is(err, ErrNotRunning) {
+		return err
+	}
+	return nil
+}
+
+// LastEventTime returns the time at which the most recent event was received
+// from Docker, or the zero time if none have been received. This can be used to
+// check that the event stream is alive.
+func (m *Monitor) LastEventTime() time.Time {
+	nanos := m.lastEvent.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// LastCallbackTime returns the time at which the callbacks were most recently
+// invoked (or, with WithAsyncCallback, queued), or the zero time if they
+// haven't been. It is safe to call concurrently, e.g. from an HTTP handler.
+func (m *Monitor) LastCallbackTime() time.Time {
+	nanos := m.lastCallback.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// CurrentCount returns the number of containers that were passed to the most
+// recent callback, i.e. the number currently matching the filter. It is safe to
+// call concurrently, e.g. from an HTTP handler.
+func (m *Monitor) CurrentCount() int {
+	return int(m.count.Load())
+}
+
+// run creates the Docker client if required, and runs the main event loop.
+func (m *Monitor) run(ctx context.Context) error {
+	dockerClient, cleanup, err := m.cfg.dockerClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.cfg.checkClient(dockerClient); err != nil {
+		_ = cleanup()
+		return err
+	}
+
+	mon := newMonitor(ctx, m.cfg, dockerClient, m.callback)
+	mon.closeClient = cleanup
+	defer func() { _ = mon.closeClient() }()
+	mon.refresh = m.refresh
+	mon.stop = m.stop
+	mon.lastEvent = &m.lastEvent
+	mon.lastCallbackTime = &m.lastCallback
+	mon.currentCount = &m.count
+	mon.filter = &m.filter
+
+	mon.log("entering main event loop")
+	return mon.run()
+}
+
+// newMonitor creates the internal monitor for the given config.
+func newMonitor(ctx context.Context, cfg *config, dockerClient DockerClient, callback Callback) *monitor {
 	var reconnect *reconnectConfig
 	if cfg.enableAutoReconnect {
 		reconnect = &reconnectConfig{
 			MinDelay:   cfg.minReconnectDelay,
 			MaxDelay:   cfg.maxReconnectDelay,
 			MaxRetries: cfg.maxReconnectRetries,
+			Jitter:     cfg.reconnectJitter,
+			StableTime: cfg.reconnectStableTime,
+			ResetAfter: cfg.reconnectResetAfter,
 		}
 	}
 
-	m := &monitor{
-		ctx:             ctx,
-		client:          dockerClient,
-		callback:        callback,
-		filter:          cfg.filter,
-		debounce:        cfg.debounce,
-		maxDebounceTime: cfg.maxDebounceTime,
-		maxIdleTime:     cfg.maxIdleTime,
-		reconnect:       reconnect,
+	actions := defaultActions
+	if len(cfg.watchedActions) > 0 {
+		actions = cfg.watchedActions
+	}
+
+	filter := &atomic.Pointer[Filter]{}
+	if cfg.filter != nil {
+		filter.Store(&cfg.filter)
+	}
+
+	var eventContainers []string
+	if cfg.scopeEventsToIDs {
+		eventContainers = cfg.watchIDs
+	}
+
+	mon := &monitor{
+		ctx:                     ctx,
+		client:                  dockerClient,
+		callback:                callback,
+		contextCallback:         cfg.contextCallback,
+		removedCallback:         cfg.removedCallback,
+		syntheticEventCallback:  cfg.syntheticEventCallback,
+		eventCallback:           cfg.eventCallback,
+		asyncCallback:           cfg.asyncCallback,
+		filter:                  filter,
+		asyncFilter:             cfg.asyncFilter,
+		watchIDs:                cfg.watchIDs,
+		listFilters:             cfg.listFilters,
+		eventFilters:            eventFilters(actions, eventContainers),
+		transform:               cfg.transform,
+		less:                    cfg.less,
+		trackStates:             cfg.trackStates,
+		hashOptions:             cfg.hashOptions(),
+		dedupDisabled:           cfg.dedupDisabled,
+		incremental:             cfg.incrementalUpdates && len(cfg.watchIDs) == 0 && !cfg.summaryOnly,
+		inspectRetries:          cfg.inspectRetries,
+		maxContainers:           cfg.maxContainers,
+		limitPolicy:             cfg.containerLimitPolicy,
+		inspectCache:            cfg.inspectCache && len(cfg.watchIDs) == 0 && !cfg.summaryOnly,
+		summaryOnly:             cfg.summaryOnly,
+		sizes:                   cfg.sizes,
+		recoverPanics:           cfg.recoverPanics,
+		tracer:                  cfg.tracer,
+		pauseOnError:            cfg.pauseOnError,
+		staleOnList:             cfg.staleOnListError,
+		name:                    cfg.name,
+		eventLogSampling:        cfg.eventLogSampling,
+		eventBuffer:             cfg.eventBuffer,
+		connectTimeout:          cfg.connectTimeout,
+		startupTimeout:          cfg.startupTimeout,
+		debounce:                cfg.debounce,
+		debounceStrategy:        cfg.debounceStrategy,
+		containerDebounce:       cfg.containerDebounce,
+		maxDebounceTime:         cfg.maxDebounceTime,
+		maxIdleTime:             cfg.maxIdleTime,
+		healthPollInterval:      cfg.healthPollInterval,
+		fullResyncInterval:      cfg.fullResyncInterval,
+		minCallbackInterval:     cfg.minCallbackInterval,
+		callbackTimeout:         cfg.callbackTimeout,
+		eventGapThreshold:       cfg.eventGapThreshold,
+		reconnect:               reconnect,
+		random:                  rand.Float64,
+		newClient:               cfg.clientFactory(),
+		recreateClientAfter:     clientRecreateAttempts,
+		checkClient:             cfg.checkClient,
+		connectionStateCallback: cfg.connectionStateCallback,
 	}
 
-	Log("entering main event loop")
-	return m.run()
+	if cfg.newClient != nil {
+		mon.recreateClientAfter = 1
+	}
+
+	if cfg.networkLabels {
+		mon.networkInspector, _ = dockerClient.(NetworkInspector)
+	}
+
+	if cfg.seedFingerprint != nil {
+		seedHash := *cfg.seedFingerprint
+		mon.previousHash = &seedHash
+	}
+	if cfg.seeded {
+		seedHash := computeHashWith(cfg.seedState, mon.hashOptions)
+		mon.previousHash = &seedHash
+		mon.previousContainers = mon.tracked(slices.Clone(cfg.seedState))
+	}
+
+	return mon
 }
 
-// newDefaultClient creates a default Docker client from the environment.
+// dockerClient returns the configured Docker client, or creates one.
 // Returns the client and a cleanup function that should be called when done.
-func newDefaultClient() (DockerClient, func() error, error) {
-	c, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+func (c *config) dockerClient(ctx context.Context) (DockerClient, func() error, error) {
+	if factory := c.clientFactory(); factory != nil {
+		return factory(ctx)
+	}
+	return c.client, func() error { return nil }, nil
+}
+
+// clientFactory returns a function that creates a new Docker client and its
+// cleanup function, or nil if a specific client has been configured without a
+// factory.
+func (c *config) clientFactory() func(context.Context) (DockerClient, func() error, error) {
+	if c.newClient != nil {
+		return c.newClient
+	}
+	if c.client != nil {
+		return nil
+	}
+	return func(context.Context) (DockerClient, func() error, error) {
+		return newDefaultClient(c.clientOptions...)
+	}
+}
+
+// checkClient checks that the Docker client supports everything required by
+// the config, returning an error wrapping ErrInvalidOption if not.
+func (c *config) checkClient(dockerClient DockerClient) error {
+	if _, ok := dockerClient.(NetworkInspector); c.networkLabels && !ok {
+		return fmt.Errorf("%w: network labels require a docker client that implements NetworkInspector", ErrInvalidOption)
+	}
+	return nil
+}
+
+// newDefaultClient creates a default Docker client from the environment, with
+// any additional options applied afterwards. Returns the client and a cleanup
+// function that should be called when done. It is a variable so that tests can
+// replace it.
+var newDefaultClient = func(opts ...client.Opt) (DockerClient, func() error, error) {
+	opts = append([]client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}, opts...)
+	c, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
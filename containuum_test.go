@@ -2,6 +2,7 @@ package containuum
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -10,8 +11,11 @@ import (
 
 	"testing/synctest"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,7 +26,13 @@ type mockDockerClient struct {
 	summaries  []container.Summary
 	inspects   map[string]container.InspectResponse
 	listErr    error
+	listBlock  chan struct{}
+	listOpts   []container.ListOptions
+	eventOpts  []events.ListOptions
 	inspectErr map[string]error
+	eventCalls int
+	listCalls  int
+	inspected  []string
 	mu         sync.Mutex
 }
 
@@ -35,29 +45,65 @@ func newMockDockerClient() *mockDockerClient {
 	}
 }
 
-func (m *mockDockerClient) Events(_ context.Context, _ events.ListOptions) (<-chan events.Message, <-chan error) {
+func (m *mockDockerClient) Events(_ context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventCalls++
+	m.eventOpts = append(m.eventOpts, options)
 	return m.eventCh, m.errCh
 }
 
-func (m *mockDockerClient) ContainerList(_ context.Context, _ container.ListOptions) ([]container.Summary, error) {
+func (m *mockDockerClient) eventCallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.eventCalls
+}
+
+func (m *mockDockerClient) listCallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listCalls
+}
+
+func (m *mockDockerClient) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	m.mu.Lock()
+	block := m.listBlock
+	m.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.listCalls++
+	m.listOpts = append(m.listOpts, options)
 	if m.listErr != nil {
 		return nil, m.listErr
 	}
 	return m.summaries, nil
 }
 
+func (m *mockDockerClient) inspectCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.inspected)
+}
+
 func (m *mockDockerClient) ContainerInspect(_ context.Context, containerID string) (container.InspectResponse, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.inspected = append(m.inspected, containerID)
 	if err, ok := m.inspectErr[containerID]; ok {
 		return container.InspectResponse{}, err
 	}
 	if inspect, ok := m.inspects[containerID]; ok {
 		return inspect, nil
 	}
-	return container.InspectResponse{}, fmt.Errorf("container not found: %s", containerID)
+	return container.InspectResponse{}, fmt.Errorf("container not found: %s: %w", containerID, cerrdefs.ErrNotFound)
 }
 
 func (m *mockDockerClient) setContainers(containers ...container.InspectResponse) {
@@ -500,7 +546,7 @@ func TestRun_WithMaxDebounceTime(t *testing.T) {
 		go func() {
 			errCh <- Run(ctx, callback,
 				WithDockerClient(mock),
-				WithDebounce(10*time.Second),
+				WithDebounce(time.Second),
 				WithMaxDebounceTime(2*time.Second),
 			)
 		}()
@@ -508,7 +554,7 @@ func TestRun_WithMaxDebounceTime(t *testing.T) {
 		start := time.Now()
 
 		// Spam events to keep debounce from expiring
-		// Events every 100ms for 2 seconds will keep resetting the 10s debounce
+		// Events every 100ms for 2 seconds will keep resetting the 1s debounce
 		// but max wait will trigger at 2s
 		for i := 0; i < 20; i++ {
 			mock.eventCh <- events.Message{Type: "container", Action: "start"}
@@ -578,3 +624,4554 @@ func TestRun_WithMaxIdleTime(t *testing.T) {
 		<-errCh
 	})
 }
+
+func TestRun_WithRemovedCallback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					Name:  "/test2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "redis:latest"},
+			},
+		)
+
+		var removedCalls [][]Container
+		mu := sync.Mutex{}
+
+		removedCallback := func(containers []Container) {
+			mu.Lock()
+			removedCalls = append(removedCalls, containers)
+			mu.Unlock()
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithRemovedCallback(removedCallback),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Empty(t, removedCalls, "removed callback should not be called initially")
+		mu.Unlock()
+
+		// Rename container1 and remove container2
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1-renamed",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		mock.eventCh <- events.Message{Type: "container", Action: "destroy"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Len(t, removedCalls, 1)
+		if assert.Len(t, removedCalls[0], 1) {
+			assert.Equal(t, "container2", removedCalls[0][0].ID)
+			assert.Equal(t, "test2", removedCalls[0][0].Name)
+			assert.Equal(t, "redis:latest", removedCalls[0][0].Image)
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithRemovedCallback_RenameIsNotRemoval(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		callCount := 0
+		removedCount := 0
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx,
+				func([]Container) {
+					mu.Lock()
+					callCount++
+					mu.Unlock()
+				},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithRemovedCallback(func([]Container) {
+					mu.Lock()
+					removedCount++
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1-renamed",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		mock.eventCh <- events.Message{Type: "container", Action: "rename"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 2, callCount)
+		assert.Equal(t, 0, removedCount)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_SortsContainers(t *testing.T) {
+	newContainer := func(id, name string) container.InspectResponse {
+		return container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    id,
+				Name:  "/" + name,
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest"},
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{
+			name: "sorts by ID by default",
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "sorts with custom less function",
+			opts: []Option{WithSort(func(a, b Container) bool { return a.Name < b.Name })},
+			want: []string{"c", "a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				mock := newMockDockerClient()
+				mock.setContainers(
+					newContainer("b", "zulu"),
+					newContainer("c", "alpha"),
+					newContainer("a", "mike"),
+				)
+
+				var receivedContainers []Container
+				callback := func(containers []Container) {
+					receivedContainers = containers
+				}
+
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, callback, append([]Option{WithDockerClient(mock)}, tt.opts...)...)
+				}()
+
+				time.Sleep(100 * time.Millisecond)
+				synctest.Wait()
+
+				var ids []string
+				for _, c := range receivedContainers {
+					ids = append(ids, c.ID)
+				}
+				assert.Equal(t, tt.want, ids)
+
+				cancel()
+				<-errCh
+			})
+		})
+	}
+}
+
+func TestRun_WithContainerTransform(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		withTimestamp := func(ts string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{
+					Image:  "nginx:latest",
+					Labels: map[string]string{"app": "web", "timestamp": ts},
+				},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(withTimestamp("1"))
+
+		callCount := 0
+		var lastContainers []Container
+		mu := sync.Mutex{}
+
+		callback := func(containers []Container) {
+			mu.Lock()
+			callCount++
+			lastContainers = containers
+			mu.Unlock()
+		}
+
+		transform := func(c Container) Container {
+			labels := make(map[string]string, len(c.Labels))
+			for k, v := range c.Labels {
+				if k != "timestamp" {
+					labels[k] = v
+				}
+			}
+			c.Labels = labels
+			return c
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, callback,
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithContainerTransform(transform),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount)
+		assert.Equal(t, map[string]string{"app": "web"}, lastContainers[0].Labels)
+		mu.Unlock()
+
+		// Only the stripped label changes
+		mock.setContainers(withTimestamp("2"))
+		mock.eventCh <- events.Message{Type: "container", Action: "update"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount, "transformed-away changes should not trigger callback")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_CleanStreamCloseWithoutReconnect(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock))
+		}()
+
+		mock.errCh <- nil
+
+		err := <-errCh
+		assert.NoError(t, err)
+		assert.Equal(t, 1, mock.eventCallCount())
+	})
+}
+
+func TestRun_CleanStreamCloseWithReconnect(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithAutoReconnect(time.Second, 10*time.Second, 0),
+			)
+		}()
+
+		synctest.Wait()
+		assert.Equal(t, 1, mock.eventCallCount())
+
+		mock.errCh <- nil
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		assert.Equal(t, 2, mock.eventCallCount(), "should have resubscribed to events")
+		select {
+		case err := <-errCh:
+			t.Fatalf("Run returned unexpectedly: %v", err)
+		default:
+		}
+
+		cancel()
+		assert.Equal(t, context.Canceled, <-errCh)
+	})
+}
+
+func TestRun_ReconnectWithUnchangedEmptyState(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithAutoReconnect(time.Second, time.Second, 0),
+			)
+		}()
+
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, calls, 1, "initial empty state should be emitted") {
+			assert.Empty(t, calls[0])
+		}
+		mu.Unlock()
+
+		for range 3 {
+			mock.errCh <- fmt.Errorf("connection reset")
+			time.Sleep(2 * time.Second)
+			synctest.Wait()
+		}
+
+		assert.Equal(t, 4, mock.listCallCount(), "should have gathered after each reconnection")
+
+		mu.Lock()
+		assert.Len(t, calls, 1, "unchanged empty state should not be emitted again after reconnecting")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_ReconnectWithUnchangedState(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		callCount := 0
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {
+				mu.Lock()
+				callCount++
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithAutoReconnect(time.Second, time.Second, 0),
+			)
+		}()
+
+		synctest.Wait()
+
+		mock.errCh <- fmt.Errorf("connection reset")
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		assert.Equal(t, 2, mock.eventCallCount(), "should have reconnected")
+		assert.Equal(t, 2, mock.listCallCount(), "should have gathered after reconnecting")
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount, "unchanged state should not be emitted again after reconnecting")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithWatchIDs(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					Name:  "/test2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "redis:latest"},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container3",
+					Name:  "/test3",
+					State: &container.State{Status: "exited"},
+				},
+				Config: &container.Config{Image: "redis:latest"},
+			},
+		)
+
+		var receivedContainers []Container
+		callback := func(containers []Container) {
+			receivedContainers = containers
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, callback,
+				WithDockerClient(mock),
+				WithWatchIDs("container1", "container3", "missing"),
+				WithFilter(StateEquals("running")),
+			)
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		synctest.Wait()
+
+		if assert.Len(t, receivedContainers, 1) {
+			assert.Equal(t, "container1", receivedContainers[0].ID)
+		}
+
+		mock.mu.Lock()
+		assert.Equal(t, 0, mock.listCalls, "ContainerList should not be called")
+		assert.Equal(t, []string{"container1", "container3", "missing"}, mock.inspected)
+		mock.mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithContainerIDs(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					Name:  "/test2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "redis:latest"},
+			},
+		)
+
+		var receivedContainers []Container
+		callback := func(containers []Container) {
+			receivedContainers = containers
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, callback,
+				WithDockerClient(mock),
+				WithContainerIDs("container1", "test3"),
+			)
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		synctest.Wait()
+
+		if assert.Len(t, receivedContainers, 1) {
+			assert.Equal(t, "container1", receivedContainers[0].ID)
+		}
+
+		mock.mu.Lock()
+		assert.Equal(t, 0, mock.listCalls, "ContainerList should not be called")
+		assert.Equal(t, []string{"container1", "test3"}, mock.inspected)
+		if assert.Len(t, mock.eventOpts, 1) {
+			assert.ElementsMatch(t, []string{"container1", "test3"}, mock.eventOpts[0].Filters.Get("container"))
+		}
+		mock.mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithWatchIDs_DoesNotScopeEvents(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithContainerIDs("container1"),
+				WithWatchIDs("container1"),
+			)
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		synctest.Wait()
+
+		mock.mu.Lock()
+		if assert.Len(t, mock.eventOpts, 1) {
+			assert.Empty(t, mock.eventOpts[0].Filters.Get("container"))
+		}
+		mock.mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithConnectionStateCallback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		type transition struct {
+			connected bool
+			err       error
+		}
+		var transitions []transition
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithAutoReconnect(time.Second, 10*time.Second, 0),
+				WithConnectionStateCallback(func(connected bool, err error) {
+					mu.Lock()
+					transitions = append(transitions, transition{connected, err})
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []transition{{true, nil}}, transitions)
+		mu.Unlock()
+
+		mock.errCh <- fmt.Errorf("connection lost")
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, transitions, 2) {
+			assert.False(t, transitions[1].connected)
+			assert.ErrorContains(t, transitions[1].err, "connection lost")
+		}
+		mu.Unlock()
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, transitions, 3) {
+			assert.Equal(t, transition{true, nil}, transitions[2])
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+
+		mu.Lock()
+		assert.Len(t, transitions, 3, "cancellation should not report a disconnection")
+		mu.Unlock()
+	})
+}
+
+func TestRun_WithConnectionStateCallback_CleanClose(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		type transition struct {
+			connected bool
+			err       error
+		}
+		var transitions []transition
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithConnectionStateCallback(func(connected bool, err error) {
+					mu.Lock()
+					transitions = append(transitions, transition{connected, err})
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		synctest.Wait()
+		mock.errCh <- nil
+		assert.NoError(t, <-errCh)
+
+		mu.Lock()
+		assert.Equal(t, []transition{{true, nil}, {false, nil}}, transitions)
+		mu.Unlock()
+	})
+}
+
+func TestRun_WithConnectionStateCallback_RepeatedFailures(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.listErr = fmt.Errorf("docker api unreachable")
+
+		var states []bool
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithAutoReconnect(time.Second, time.Second, 3),
+				WithConnectionStateCallback(func(connected bool, err error) {
+					mu.Lock()
+					states = append(states, connected)
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		err := <-errCh
+		assert.ErrorContains(t, err, "docker api unreachable")
+
+		mu.Lock()
+		assert.Empty(t, states, "should not report changes if never connected")
+		mu.Unlock()
+	})
+}
+
+func TestMonitor_StopWaitsForCallback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		callbackStarted := make(chan struct{})
+		releaseCallback := make(chan struct{})
+		callbackDone := false
+
+		m := New(func([]Container) {
+			close(callbackStarted)
+			<-releaseCallback
+			callbackDone = true
+		}, WithDockerClient(mock))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+
+		<-callbackStarted
+
+		stopped := make(chan struct{})
+		go func() {
+			assert.NoError(t, m.Stop(context.Background()))
+			close(stopped)
+		}()
+
+		synctest.Wait()
+		select {
+		case <-stopped:
+			t.Fatal("Stop returned while callback was in progress")
+		default:
+		}
+
+		close(releaseCallback)
+		<-stopped
+
+		assert.True(t, callbackDone)
+		assert.NoError(t, <-errCh)
+	})
+}
+
+func TestMonitor_StopWaitsForGather(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		var mu sync.Mutex
+		calls := 0
+		m := New(func([]Container) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+		}, WithDockerClient(mock), WithDedupDisabled())
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+		synctest.Wait()
+
+		mock.mu.Lock()
+		mock.listBlock = make(chan struct{})
+		mock.mu.Unlock()
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "c1"}}
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- m.Stop(context.Background())
+		}()
+
+		synctest.Wait()
+		select {
+		case <-stopped:
+			t.Fatal("Stop returned while gather was in progress")
+		default:
+		}
+
+		close(mock.listBlock)
+		assert.NoError(t, <-stopped)
+		assert.NoError(t, <-errCh)
+		mu.Lock()
+		assert.Equal(t, 2, calls, "in-flight gather should invoke the callback")
+		mu.Unlock()
+	})
+}
+
+func TestMonitor_StopAbandonsGatherWhenContextDone(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+		mock.listBlock = make(chan struct{})
+		defer close(mock.listBlock)
+
+		called := false
+		m := New(func([]Container) { called = true }, WithDockerClient(mock))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+		synctest.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		assert.ErrorIs(t, m.Stop(ctx), context.DeadlineExceeded)
+		assert.Equal(t, 5*time.Second, time.Since(start))
+		assert.NoError(t, <-errCh)
+		assert.False(t, called)
+	})
+}
+
+func TestMonitor_StopIsIdempotent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		m := New(func([]Container) {}, WithDockerClient(mock))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+
+		synctest.Wait()
+		assert.NoError(t, m.Stop(context.Background()))
+		assert.NoError(t, m.Stop(context.Background()))
+
+		assert.NoError(t, <-errCh)
+		assert.ErrorIs(t, m.Start(context.Background()), ErrAlreadyStarted)
+	})
+}
+
+func TestMonitor_StopBeforeStart(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		called := false
+		m := New(func([]Container) { called = true }, WithDockerClient(mock))
+		assert.NoError(t, m.Stop(context.Background()))
+
+		assert.NoError(t, m.Start(context.Background()))
+		assert.False(t, called)
+	})
+}
+
+func TestMonitor_ContextCancellation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		m := New(func([]Container) {}, WithDockerClient(mock))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(ctx)
+		}()
+
+		synctest.Wait()
+		cancel()
+
+		assert.Equal(t, context.Canceled, <-errCh)
+		assert.NoError(t, m.Stop(context.Background()))
+	})
+}
+
+func TestRun_EventWithEmptyActorID(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		var lastContainers []Container
+		callCount := 0
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				callCount++
+				lastContainers = containers
+				mu.Unlock()
+			}, WithDockerClient(mock), WithDebounce(10*time.Millisecond))
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		mock.eventCh <- events.Message{Type: "network", Action: "connect", Actor: events.Actor{ID: ""}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 2, callCount)
+		assert.Len(t, lastContainers, 1)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestMonitor_RefreshNow(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		callCount := 0
+		var lastContainers []Container
+		mu := sync.Mutex{}
+
+		m := New(func(containers []Container) {
+			mu.Lock()
+			callCount++
+			lastContainers = containers
+			mu.Unlock()
+		}, WithDockerClient(mock))
+
+		assert.ErrorIs(t, m.RefreshNow(), ErrNotRunning)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+		synctest.Wait()
+
+		// Unchanged state is deduplicated
+		assert.NoError(t, m.RefreshNow())
+		mu.Lock()
+		assert.Equal(t, 1, callCount)
+		mu.Unlock()
+
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		assert.NoError(t, m.RefreshNow())
+		mu.Lock()
+		assert.Equal(t, 2, callCount)
+		assert.Len(t, lastContainers, 1)
+		mu.Unlock()
+
+		assert.NoError(t, m.Stop(context.Background()))
+		assert.NoError(t, <-errCh)
+		assert.ErrorIs(t, m.RefreshNow(), ErrNotRunning)
+	})
+}
+
+func TestMonitor_SetFilter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Labels: map[string]string{"env": "prod"}},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					Name:  "/test2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Labels: map[string]string{"env": "dev"}},
+			},
+		)
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		m := New(func(containers []Container) {
+			mu.Lock()
+			calls = append(calls, containers)
+			mu.Unlock()
+		}, WithDockerClient(mock), WithFilter(LabelEquals("env", "prod")))
+
+		// Setting the filter before starting doesn't refresh
+		assert.NoError(t, m.SetFilter(LabelEquals("env", "prod")))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+		synctest.Wait()
+
+		assert.NoError(t, m.SetFilter(LabelEquals("env", "dev")))
+		assert.NoError(t, m.SetFilter(nil))
+
+		mu.Lock()
+		if assert.Len(t, calls, 3) {
+			if assert.Len(t, calls[0], 1) {
+				assert.Equal(t, "container1", calls[0][0].ID)
+			}
+			if assert.Len(t, calls[1], 1) {
+				assert.Equal(t, "container2", calls[1][0].ID)
+			}
+			assert.Len(t, calls[2], 2)
+		}
+		mu.Unlock()
+
+		assert.NoError(t, m.Stop(context.Background()))
+		assert.NoError(t, <-errCh)
+		assert.NoError(t, m.SetFilter(nil))
+	})
+}
+
+func TestMonitor_WithDedupDisabled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest"},
+		})
+
+		callCount := 0
+		mu := sync.Mutex{}
+
+		m := New(func([]Container) {
+			mu.Lock()
+			callCount++
+			mu.Unlock()
+		}, WithDockerClient(mock), WithDedupDisabled())
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+		synctest.Wait()
+
+		assert.NoError(t, m.RefreshNow())
+		assert.NoError(t, m.RefreshNow())
+
+		mu.Lock()
+		assert.Equal(t, 3, callCount, "callback should be invoked for every gather")
+		mu.Unlock()
+
+		assert.NoError(t, m.Stop(context.Background()))
+		assert.NoError(t, <-errCh)
+	})
+}
+
+func TestRun_WithContextCallback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		callbackStarted := make(chan struct{})
+		var callbackErr error
+		var received []Container
+
+		m := New(nil, WithDockerClient(mock), WithContextCallback(func(ctx context.Context, containers []Container) {
+			received = containers
+			close(callbackStarted)
+			<-ctx.Done()
+			callbackErr = ctx.Err()
+		}))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+
+		<-callbackStarted
+		assert.Len(t, received, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.ErrorIs(t, m.Stop(ctx), context.DeadlineExceeded)
+		assert.ErrorIs(t, callbackErr, context.Canceled)
+		assert.NoError(t, <-errCh)
+	})
+}
+
+func TestRun_WithSyntheticEventCallback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		container1 := container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest"},
+		}
+		container2 := container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container2",
+				Name:  "/test2",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "redis:latest"},
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(container1)
+
+		var received [][]events.Message
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, nil,
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithSyntheticEventCallback(func(messages []events.Message) {
+					mu.Lock()
+					received = append(received, messages)
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// Add container2
+		mock.setContainers(container1, container2)
+		mock.eventCh <- events.Message{Type: "container", Action: "create"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// Remove container1
+		mock.setContainers(container2)
+		mock.eventCh <- events.Message{Type: "container", Action: "destroy"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, received, 3) {
+			if assert.Len(t, received[1], 1) {
+				assert.Equal(t, events.ContainerEventType, received[1][0].Type)
+				assert.Equal(t, events.ActionCreate, received[1][0].Action)
+				assert.Equal(t, "container2", received[1][0].Actor.ID)
+				assert.Equal(t, "test2", received[1][0].Actor.Attributes["name"])
+			}
+			if assert.Len(t, received[2], 1) {
+				assert.Equal(t, events.ActionDestroy, received[2][0].Action)
+				assert.Equal(t, "container1", received[2][0].Actor.ID)
+			}
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithTrackStates(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/running",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					Name:  "/exited",
+					State: &container.State{Status: "exited"},
+				},
+				Config: &container.Config{Image: "redis:latest"},
+			},
+		)
+
+		var received []Container
+		var removed []Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx,
+				func(containers []Container) {
+					mu.Lock()
+					received = containers
+					mu.Unlock()
+				},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithTrackStates("running"),
+				WithRemovedCallback(func(containers []Container) {
+					mu.Lock()
+					removed = append(removed, containers...)
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Len(t, received, 2, "untracked containers should still be emitted")
+		mu.Unlock()
+
+		mock.setContainers()
+		mock.eventCh <- events.Message{Type: "container", Action: "destroy"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, removed, 1, "only tracked containers should be remembered") {
+			assert.Equal(t, "container1", removed[0].ID)
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithHashFields(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(state, heartbeat string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{
+					Image:  "nginx:latest",
+					Labels: map[string]string{"heartbeat": heartbeat},
+				},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(newContainer("running", "1"))
+
+		callCount := 0
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx,
+				func([]Container) {
+					mu.Lock()
+					callCount++
+					mu.Unlock()
+				},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithHashFields(FieldID, FieldState, FieldPorts),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// Label-only change
+		mock.setContainers(newContainer("running", "2"))
+		mock.eventCh <- events.Message{Type: "container", Action: "update"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount, "label-only change should not invoke callback")
+		mu.Unlock()
+
+		// State change
+		mock.setContainers(newContainer("exited", "2"))
+		mock.eventCh <- events.Message{Type: "container", Action: "die"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 2, callCount, "state change should invoke callback")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithSetIdentityDedup(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(id, state, version string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					Name:  "/" + id,
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{
+					Image:  "nginx:latest",
+					Labels: map[string]string{"version": version},
+				},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(newContainer("container1", "running", "1"))
+
+		callCount := 0
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx,
+				func([]Container) {
+					mu.Lock()
+					callCount++
+					mu.Unlock()
+				},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithFilter(StateEquals("running")),
+				WithSetIdentityDedup(),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// Detail change on a matching container
+		mock.setContainers(newContainer("container1", "running", "2"))
+		mock.eventCh <- events.Message{Type: "container", Action: "update"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount, "detail change should not invoke callback")
+		mu.Unlock()
+
+		// Container joins the matching set
+		mock.setContainers(newContainer("container1", "running", "2"), newContainer("container2", "running", "1"))
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 2, callCount, "added container should invoke callback")
+		mu.Unlock()
+
+		// Container leaves the matching set
+		mock.setContainers(newContainer("container1", "running", "2"), newContainer("container2", "exited", "1"))
+		mock.eventCh <- events.Message{Type: "container", Action: "die"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 3, callCount, "removed container should invoke callback")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithEventLogSampling(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		eventLogs := 0
+		oldLog := Log
+		Log = func(msg string, _ ...any) {
+			if msg == "Received event from docker" {
+				mu.Lock()
+				eventLogs++
+				mu.Unlock()
+			}
+		}
+		defer func() { Log = oldLog }()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithEventLogSampling(10),
+			)
+		}()
+
+		for i := 0; i < 100; i++ {
+			mock.eventCh <- events.Message{Type: "container", Action: "start", Actor: events.Actor{ID: "container1"}}
+		}
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 10, eventLogs)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithEventBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		buffer      int
+		wantRefresh bool
+	}{
+		{name: "large enough", buffer: 100, wantRefresh: false},
+		{name: "overflowing", buffer: 5, wantRefresh: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				var mu sync.Mutex
+				overflowed := false
+				oldLog := Log
+				Log = func(msg string, _ ...any) {
+					if msg == "Event buffer overflowed, will perform full refresh" {
+						mu.Lock()
+						overflowed = true
+						mu.Unlock()
+					}
+				}
+				defer func() { Log = oldLog }()
+
+				mock := newMockDockerClient()
+				mock.setContainers()
+
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, func([]Container) {},
+						WithDockerClient(mock),
+						WithDebounce(10*time.Millisecond),
+						WithEventBuffer(tt.buffer),
+					)
+				}()
+
+				time.Sleep(50 * time.Millisecond)
+				synctest.Wait()
+
+				// Block the refresh triggered by the first event, so the loop
+				// can't receive any more events.
+				unblock := make(chan struct{})
+				mock.mu.Lock()
+				mock.listBlock = unblock
+				mock.mu.Unlock()
+				mock.eventCh <- events.Message{Type: "container", Action: "start", Actor: events.Actor{ID: "container1"}}
+				time.Sleep(50 * time.Millisecond)
+				synctest.Wait()
+
+				sent := make(chan struct{})
+				go func() {
+					defer close(sent)
+					for i := 0; i < 50; i++ {
+						mock.eventCh <- events.Message{Type: "container", Action: "start", Actor: events.Actor{ID: "container1"}}
+					}
+				}()
+				synctest.Wait()
+
+				select {
+				case <-sent:
+				default:
+					t.Fatal("events should be drained from the client while refreshing")
+				}
+
+				close(unblock)
+				time.Sleep(50 * time.Millisecond)
+				synctest.Wait()
+
+				mu.Lock()
+				assert.Equal(t, tt.wantRefresh, overflowed)
+				mu.Unlock()
+
+				select {
+				case err := <-errCh:
+					t.Fatalf("monitor should keep running, but returned %v", err)
+				default:
+				}
+
+				cancel()
+				<-errCh
+			})
+		})
+	}
+}
+
+func TestRun_WithAsyncFilter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(id, state, image string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{Image: image},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			newContainer("approved", "running", "nginx:latest"),
+			newContainer("unapproved", "running", "evil:latest"),
+			newContainer("failing", "running", "unknown:latest"),
+			newContainer("stopped", "exited", "nginx:latest"),
+		)
+
+		var mu sync.Mutex
+		var checked []string
+		var ids []string
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, c := range containers {
+					ids = append(ids, c.ID)
+				}
+			},
+				WithDockerClient(mock),
+				WithFilter(StateEquals(StateRunning)),
+				WithAsyncFilter(func(ctx context.Context, c Container) (bool, error) {
+					assert.NoError(t, ctx.Err())
+					mu.Lock()
+					checked = append(checked, c.ID)
+					mu.Unlock()
+					if c.Image == "unknown:latest" {
+						return true, errors.New("lookup failed")
+					}
+					return c.Image == "nginx:latest", nil
+				}),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"approved"}, ids)
+		assert.ElementsMatch(t, []string{"approved", "unapproved", "failing"}, checked, "async filter should only be applied to containers matching the filter")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithName(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var logs [][]any
+		var messages []string
+		oldLog := Log
+		Log = func(msg string, keysAndValues ...any) {
+			mu.Lock()
+			logs = append(logs, keysAndValues)
+			messages = append(messages, msg)
+			mu.Unlock()
+		}
+		defer func() { Log = oldLog }()
+
+		mock := newMockDockerClient()
+		mock.setContainers(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{},
+			NetworkSettings: &container.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"frontend":       {NetworkID: "net1", IPAddress: "172.18.0.2"},
+					"frontend-alias": {NetworkID: "net1"},
+				},
+			},
+		})
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock), WithName("proxy"))
+		}()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start", Actor: events.Actor{ID: "container1"}}
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		cancel()
+		<-errCh
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEmpty(t, logs)
+		duplicates := 0
+		for _, msg := range messages {
+			if msg == "Replacing duplicate network entry" || msg == "Ignoring duplicate network entry" {
+				duplicates++
+			}
+		}
+		assert.Equal(t, 2, duplicates, "duplicate networks should be logged by the monitor")
+		for _, kv := range logs {
+			if assert.GreaterOrEqual(t, len(kv), 2) {
+				assert.Equal(t, []any{"monitor", "proxy"}, kv[:2])
+			}
+		}
+	})
+}
+
+func TestRun_WithIgnoredLabels(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(configHash string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{
+					Image: "nginx:latest",
+					Labels: map[string]string{
+						"app":                            "web",
+						"com.docker.compose.config-hash": configHash,
+					},
+				},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(newContainer("abc"))
+
+		callCount := 0
+		var lastContainers []Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx,
+				func(containers []Container) {
+					mu.Lock()
+					callCount++
+					lastContainers = containers
+					mu.Unlock()
+				},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithIgnoredLabels("com.docker.compose.config-hash"),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount)
+		assert.Equal(t, "abc", lastContainers[0].Labels["com.docker.compose.config-hash"], "ignored labels should still be present")
+		mu.Unlock()
+
+		mock.setContainers(newContainer("def"))
+		mock.eventCh <- events.Message{Type: "container", Action: "update"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount, "changes to ignored labels should not invoke callback")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithContextCallback_ContextCancellation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		callbackStarted := make(chan struct{})
+		var callbackErr error
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, nil,
+				WithDockerClient(mock),
+				WithContextCallback(func(ctx context.Context, containers []Container) {
+					close(callbackStarted)
+					<-ctx.Done()
+					callbackErr = ctx.Err()
+				}),
+			)
+		}()
+
+		<-callbackStarted
+		cancel()
+
+		assert.Equal(t, context.Canceled, <-errCh)
+		assert.ErrorIs(t, callbackErr, context.Canceled)
+	})
+}
+
+func TestRun_WithAsyncCallback_LatestStateWins(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		setName := func(name string) {
+			mock.setContainers(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/" + name,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			})
+		}
+		setName("initial")
+
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var names []string
+
+		callback := func(containers []Container) {
+			mu.Lock()
+			names = append(names, containers[0].Name)
+			mu.Unlock()
+			<-release
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, callback, WithDockerClient(mock), WithAsyncCallback(), WithDebounce(10*time.Millisecond))
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// The callback is blocked on the initial state; make several changes.
+		for _, name := range []string{"second", "third", "fourth"} {
+			setName(name)
+			mock.eventCh <- events.Message{Type: "container", Action: "start", Actor: events.Actor{ID: "container1"}}
+			time.Sleep(50 * time.Millisecond)
+			synctest.Wait()
+		}
+
+		close(release)
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"initial", "fourth"}, names)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithRecoverCallbackPanics(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var panicLogs int
+		oldLog := Log
+		Log = func(msg string, _ ...any) {
+			if msg == "Recovered from panic in callback" {
+				mu.Lock()
+				panicLogs++
+				mu.Unlock()
+			}
+		}
+		defer func() { Log = oldLog }()
+
+		mock := newMockDockerClient()
+		mock.setContainers(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{},
+		})
+
+		var calls int
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls++
+				first := calls == 1
+				mu.Unlock()
+				if first {
+					var m map[string]string
+					m["boom"] = "nil map"
+				}
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithRecoverCallbackPanics(),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.setContainers(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "exited"},
+			},
+			Config: &container.Config{},
+		})
+		mock.eventCh <- events.Message{Type: "container", Action: "die", Actor: events.Actor{ID: "container1"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 2, calls, "monitor should keep running after the callback panics")
+		assert.Equal(t, 1, panicLogs)
+		mu.Unlock()
+
+		cancel()
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+	})
+}
+
+func TestRun_WithHealthPollInterval(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		setHealth := func(health string) {
+			mock.setContainers(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running", Health: &container.Health{Status: health}},
+				},
+				Config: &container.Config{},
+			})
+		}
+		setHealth("starting")
+
+		var mu sync.Mutex
+		var healths []string
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				healths = append(healths, containers[0].Health)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithMaxIdleTime(time.Hour),
+				WithHealthPollInterval(time.Second),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// No event is sent for the health change
+		setHealth("healthy")
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"starting", "healthy"}, healths)
+		mu.Unlock()
+
+		// Polling stops once nothing is starting
+		lists := mock.listCallCount()
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, lists, mock.listCallCount())
+
+		cancel()
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+	})
+}
+
+func TestRun_WithCallbackTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		setState := func(state string) {
+			mock.setContainers(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{},
+			})
+		}
+		setState("running")
+
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var states []string
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				states = append(states, containers[0].State)
+				first := len(states) == 1
+				mu.Unlock()
+				if first {
+					<-release
+				}
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithCallbackTimeout(time.Second),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		setState("exited")
+		mock.eventCh <- events.Message{Type: "container", Action: "die", Actor: events.Actor{ID: "container1"}}
+		time.Sleep(500 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"running"}, states, "event loop should wait for the callback until the timeout")
+		mu.Unlock()
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"running", "exited"}, states, "event loop should proceed after the timeout")
+		mu.Unlock()
+
+		close(release)
+		cancel()
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+	})
+}
+
+// recordingTracer is a Tracer that records the spans started with it.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer *recordingTracer
+	name   string
+	parent *recordedSpan
+	attrs  []Attribute
+	err    error
+	ended  bool
+}
+
+type recordedSpanKey struct{}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(recordedSpanKey{}).(*recordedSpan)
+	span := &recordedSpan{tracer: r, name: name, parent: parent, attrs: attrs}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+func (r *recordingTracer) named(name string) []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []*recordedSpan
+	for _, span := range r.spans {
+		if span.name == name {
+			res = append(res, span)
+		}
+	}
+	return res
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *recordedSpan) Fail(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+func TestRun_WithTracer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tracer := &recordingTracer{}
+
+		mock := newMockDockerClient()
+		setState := func(state string) {
+			mock.setContainers(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{},
+			})
+		}
+		setState("running")
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithTracer(tracer),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		setState("exited")
+		mock.eventCh <- events.Message{Type: "container", Action: "die", Actor: events.Actor{ID: "container1"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		cancel()
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+
+		gathers := tracer.named("containuum.gather")
+		callbacks := tracer.named("containuum.callback")
+
+		if assert.Len(t, gathers, 2) {
+			assert.Contains(t, gathers[0].attrs, Attribute{Key: "containuum.containers", Value: 1})
+			assert.True(t, gathers[0].ended)
+			assert.NoError(t, gathers[0].err)
+		}
+		if assert.Len(t, callbacks, 2) {
+			for i := range callbacks {
+				assert.Same(t, gathers[i], callbacks[i].parent)
+				assert.Contains(t, callbacks[i].attrs, Attribute{Key: "containuum.callback", Value: "main"})
+				assert.True(t, callbacks[i].ended)
+			}
+		}
+	})
+}
+
+func TestRun_WithTracer_FailedGather(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		tracer := &recordingTracer{}
+
+		mock := newMockDockerClient()
+		mock.listErr = errors.New("docker api unreachable")
+
+		err := Run(context.Background(), func([]Container) {}, WithDockerClient(mock), WithTracer(tracer))
+		assert.ErrorContains(t, err, "docker api unreachable")
+
+		gathers := tracer.named("containuum.gather")
+		if assert.Len(t, gathers, 1) {
+			assert.ErrorContains(t, gathers[0].err, "docker api unreachable")
+			assert.True(t, gathers[0].ended)
+		}
+	})
+}
+
+func TestRun_WithAsyncCallback_DoesNotBlockEventLoop(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{},
+		})
+
+		callback := func([]Container) {
+			time.Sleep(time.Hour)
+		}
+
+		var mu sync.Mutex
+		var removals int
+		removedCallback := func([]Container) {
+			mu.Lock()
+			removals++
+			mu.Unlock()
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(
+				ctx,
+				callback,
+				WithDockerClient(mock),
+				WithAsyncCallback(),
+				WithRemovedCallback(removedCallback),
+				WithDebounce(10*time.Millisecond),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// Remove the container while the callback is sleeping; the event loop
+		// should still process the event and invoke the removed callback.
+		mock.setContainers()
+		mock.eventCh <- events.Message{Type: "container", Action: "destroy", Actor: events.Actor{ID: "container1"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, removals)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestConfig_DockerClient_WithClientOptions(t *testing.T) {
+	cfg := defaultConfig()
+	WithClientOptions(client.WithHost("tcp://docker.example.com:2376"), client.WithVersion("1.44"))(cfg)
+
+	dockerClient, cleanup, err := cfg.dockerClient(context.Background())
+	assert.NoError(t, err)
+	defer func() { _ = cleanup() }()
+
+	c, ok := dockerClient.(*client.Client)
+	if assert.True(t, ok) {
+		assert.Equal(t, "tcp://docker.example.com:2376", c.DaemonHost())
+		assert.Equal(t, "1.44", c.ClientVersion())
+	}
+}
+
+func TestConfig_DockerClient_WithInvalidClientOptions(t *testing.T) {
+	cfg := defaultConfig()
+	WithClientOptions(client.WithHost("not a valid host"))(cfg)
+
+	_, _, err := cfg.dockerClient(context.Background())
+	assert.Error(t, err)
+}
+
+func TestRun_WithConnectTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.listBlock = make(chan struct{})
+
+		called := false
+		start := time.Now()
+		err := Run(context.Background(), func([]Container) {
+			called = true
+		}, WithDockerClient(mock), WithConnectTimeout(5*time.Second))
+
+		assert.ErrorIs(t, err, ErrConnectTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 5*time.Second, time.Since(start))
+		assert.False(t, called)
+	})
+}
+
+func TestRun_WithConnectTimeout_OnlyAppliesToInitialGather(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock), WithConnectTimeout(5*time.Second))
+		}()
+
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+
+		select {
+		case err := <-errCh:
+			t.Fatalf("Run returned unexpectedly: %v", err)
+		default:
+		}
+
+		cancel()
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+	})
+}
+
+func TestRun_RecreatesClientAfterRepeatedFailures(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		broken := newMockDockerClient()
+		broken.listErr = errors.New("connection refused")
+		working := newMockDockerClient()
+		working.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+
+		var mu sync.Mutex
+		clients := []*mockDockerClient{broken, working}
+		var created int
+		var closed []int
+
+		var calls [][]Container
+		monitor := New(func(containers []Container) {
+			mu.Lock()
+			calls = append(calls, containers)
+			mu.Unlock()
+		}, WithAutoReconnect(time.Second, time.Second, 0))
+
+		oldNewDefaultClient := newDefaultClient
+		defer func() { newDefaultClient = oldNewDefaultClient }()
+		newDefaultClient = func(...client.Opt) (DockerClient, func() error, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			c := clients[created]
+			created++
+			n := created
+			return c, func() error {
+				mu.Lock()
+				closed = append(closed, n)
+				mu.Unlock()
+				return nil
+			}, nil
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- monitor.Start(ctx)
+		}()
+
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 2, created, "client should be recreated once")
+		assert.Equal(t, []int{1}, closed, "old client should be closed when replaced")
+		if assert.Len(t, calls, 1) {
+			assert.Equal(t, "container1", calls[0][0].ID)
+		}
+		mu.Unlock()
+		assert.Equal(t, clientRecreateAttempts, broken.listCallCount())
+
+		cancel()
+		<-errCh
+
+		mu.Lock()
+		assert.Equal(t, []int{1, 2}, closed, "new client should be closed on shutdown")
+		mu.Unlock()
+	})
+}
+
+func TestRun_UserClientIsNotRecreated(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.listErr = errors.New("connection refused")
+
+		monitor := New(func([]Container) {},
+			WithDockerClient(mock),
+			WithAutoReconnect(time.Second, time.Second, 5),
+		)
+
+		oldNewDefaultClient := newDefaultClient
+		defer func() { newDefaultClient = oldNewDefaultClient }()
+		newDefaultClient = func(...client.Opt) (DockerClient, func() error, error) {
+			t.Error("client should not be recreated")
+			return nil, nil, errors.New("unexpected")
+		}
+
+		err := monitor.Start(ctx)
+		assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+		assert.Equal(t, 6, mock.listCallCount())
+	})
+}
+
+func TestRun_WithClientFactory(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newBroken := func() *mockDockerClient {
+			mock := newMockDockerClient()
+			mock.listErr = errors.New("connection refused")
+			return mock
+		}
+		working := newMockDockerClient()
+		working.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+		ignored := newMockDockerClient()
+
+		var mu sync.Mutex
+		clients := []*mockDockerClient{newBroken(), newBroken(), working}
+		var created int
+		var closed []int
+
+		var calls [][]Container
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(ignored),
+				WithAutoReconnect(time.Second, time.Second, 0),
+				WithClientFactory(func(context.Context) (DockerClient, func() error, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					c := clients[created]
+					created++
+					n := created
+					return c, func() error {
+						mu.Lock()
+						closed = append(closed, n)
+						mu.Unlock()
+						return nil
+					}, nil
+				}),
+			)
+		}()
+
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 3, created, "factory should be called on start and each reconnect")
+		assert.Equal(t, []int{1, 2}, closed, "old clients should be closed when replaced")
+		if assert.Len(t, calls, 1) {
+			assert.Equal(t, "container1", calls[0][0].ID)
+		}
+		mu.Unlock()
+		assert.Equal(t, 0, ignored.eventCallCount(), "factory should take precedence over WithDockerClient")
+
+		cancel()
+		<-errCh
+
+		mu.Lock()
+		assert.Equal(t, []int{1, 2, 3}, closed, "current client should be closed on shutdown")
+		mu.Unlock()
+	})
+}
+
+func TestRun_WithClientFactory_Error(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.listErr = errors.New("connection refused")
+
+		var mu sync.Mutex
+		created := 0
+		closed := 0
+
+		err := Run(ctx, func([]Container) {},
+			WithAutoReconnect(time.Second, time.Second, 3),
+			WithClientFactory(func(context.Context) (DockerClient, func() error, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				created++
+				if created > 1 {
+					return nil, nil, errors.New("daemon unavailable")
+				}
+				return mock, func() error {
+					mu.Lock()
+					closed++
+					mu.Unlock()
+					return nil
+				}, nil
+			}),
+		)
+		assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, 4, created)
+		assert.Equal(t, 1, closed, "existing client should be kept, and closed on shutdown")
+		assert.Equal(t, 4, mock.listCallCount())
+	})
+}
+
+func TestRun_WithReconnectStableTime(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		// The stream fails repeatedly, immediately after each connection.
+		for range 5 {
+			mock.errCh <- fmt.Errorf("connection reset")
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithAutoReconnect(10*time.Millisecond, 10*time.Millisecond, 0),
+				WithReconnectStableTime(time.Second),
+			)
+		}()
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		assert.Equal(t, 6, mock.eventCallCount(), "should have resubscribed after each failure")
+		assert.Equal(t, 2, mock.listCallCount(), "should only gather initially and once the stream is stable")
+
+		cancel()
+		assert.Equal(t, context.Canceled, <-errCh)
+	})
+}
+
+func TestRun_WithReconnectResetAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection time.Duration
+		wantErr    error
+	}{
+		{
+			name:       "short connections don't reset retries",
+			connection: 25 * time.Second,
+			wantErr:    ErrMaxRetriesExceeded,
+		},
+		{
+			name:       "healthy connections reset retries",
+			connection: 35 * time.Second,
+			wantErr:    context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				mock := newMockDockerClient()
+				mock.setContainers()
+
+				// Each connection lasts for the given time before failing.
+				go func() {
+					for range 4 {
+						time.Sleep(tt.connection)
+						mock.errCh <- fmt.Errorf("connection reset")
+					}
+				}()
+
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, func([]Container) {},
+						WithDockerClient(mock),
+						WithAutoReconnect(10*time.Millisecond, 10*time.Millisecond, 2),
+						WithReconnectResetAfter(30*time.Second),
+					)
+				}()
+
+				time.Sleep(5 * tt.connection)
+				synctest.Wait()
+				cancel()
+
+				assert.ErrorIs(t, <-errCh, tt.wantErr)
+			})
+		})
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	mock := newMockDockerClient()
+	called := false
+
+	err := Run(context.Background(), func([]Container) {
+		called = true
+	}, WithDockerClient(mock), WithDebounce(time.Minute), WithMaxDebounceTime(time.Second))
+
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	assert.False(t, called)
+	assert.Equal(t, 0, mock.eventCallCount())
+}
+
+func TestRun_HealthTransition(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		withHealth := func(status container.HealthStatus) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:   "container1",
+					Name: "/test1",
+					State: &container.State{
+						Status: "running",
+						Health: &container.Health{Status: status},
+					},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(withHealth(container.Starting))
+
+		var mu sync.Mutex
+		var health []string
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				health = append(health, containers[0].Health)
+				mu.Unlock()
+			}, WithDockerClient(mock))
+		}()
+
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+
+		mock.setContainers(withHealth(container.Healthy))
+		mock.eventCh <- events.Message{
+			Type:   events.ContainerEventType,
+			Action: events.ActionHealthStatusHealthy,
+			Actor:  events.Actor{ID: "container1"},
+		}
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"starting", "healthy"}, health)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithIncrementalUpdates(t *testing.T) {
+	newContainer := func(id, state string) container.InspectResponse {
+		return container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    id,
+				Name:  "/" + id,
+				State: &container.State{Status: state},
+			},
+			Config: &container.Config{Image: "nginx:latest"},
+		}
+	}
+
+	// inspectsForEvent returns the number of inspects performed when a single
+	// container changes state, and the containers passed to the final callback.
+	inspectsForEvent := func(t *testing.T, opts ...Option) (int, []Container) {
+		var inspects int
+		var last []Container
+
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mock := newMockDockerClient()
+			mock.setContainers(
+				newContainer("c1", "running"),
+				newContainer("c2", "running"),
+				newContainer("c3", "running"),
+				newContainer("c4", "running"),
+				newContainer("c5", "running"),
+			)
+
+			var mu sync.Mutex
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- Run(ctx, func(containers []Container) {
+					mu.Lock()
+					last = containers
+					mu.Unlock()
+				}, append([]Option{WithDockerClient(mock)}, opts...)...)
+			}()
+
+			time.Sleep(200 * time.Millisecond)
+			synctest.Wait()
+			before := mock.inspectCount()
+
+			mock.setContainers(
+				newContainer("c1", "running"),
+				newContainer("c2", "running"),
+				newContainer("c3", "exited"),
+				newContainer("c4", "running"),
+				newContainer("c5", "running"),
+			)
+			mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionDie, Actor: events.Actor{ID: "c3"}}
+			time.Sleep(200 * time.Millisecond)
+			synctest.Wait()
+
+			inspects = mock.inspectCount() - before
+
+			cancel()
+			<-errCh
+		})
+
+		return inspects, last
+	}
+
+	fullInspects, fullContainers := inspectsForEvent(t)
+	incrementalInspects, incrementalContainers := inspectsForEvent(t, WithIncrementalUpdates())
+
+	assert.Equal(t, 5, fullInspects)
+	assert.Equal(t, 1, incrementalInspects)
+	assert.Equal(t, fullContainers, incrementalContainers)
+	if assert.Len(t, incrementalContainers, 5) {
+		assert.Equal(t, "exited", incrementalContainers[2].State)
+	}
+}
+
+func TestRun_WithIncrementalUpdates_RemovedAndFiltered(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(id, state string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					Name:  "/" + id,
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(newContainer("c1", "running"), newContainer("c2", "running"), newContainer("c3", "running"))
+
+		var mu sync.Mutex
+		var ids []string
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				ids = ids[:0]
+				for _, c := range containers {
+					ids = append(ids, c.ID)
+				}
+				mu.Unlock()
+			}, WithDockerClient(mock), WithIncrementalUpdates(), WithFilter(StateEquals("running")))
+		}()
+
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+
+		// c1 is destroyed, c2 stops (and so no longer matches the filter), and c4 starts.
+		mock.mu.Lock()
+		delete(mock.inspects, "c1")
+		mock.mu.Unlock()
+		mock.setContainers(newContainer("c2", "exited"), newContainer("c3", "running"), newContainer("c4", "running"))
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionDestroy, Actor: events.Actor{ID: "c1"}}
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionDie, Actor: events.Actor{ID: "c2"}}
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "c4"}}
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"c3", "c4"}, ids)
+		mu.Unlock()
+		assert.Equal(t, 1, mock.listCallCount(), "should not have listed containers again")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithIncrementalUpdates_FullRefreshForUnattributedEvents(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock), WithIncrementalUpdates())
+		}()
+
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 1, mock.listCallCount())
+
+		mock.eventCh <- events.Message{Type: events.NetworkEventType, Action: events.ActionCreate, Actor: events.Actor{ID: "net1"}}
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+
+		assert.Equal(t, 2, mock.listCallCount(), "should have performed a full refresh")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithFullResyncInterval(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithDebounce(time.Second),
+				WithMaxDebounceTime(time.Hour),
+				WithFullResyncInterval(5*time.Second),
+			)
+		}()
+
+		synctest.Wait()
+		assert.Equal(t, 1, mock.listCallCount())
+
+		// Events every 500ms keep resetting the debounce, so the only refreshes
+		// should come from the resync interval.
+		for range 24 {
+			mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "c1"}}
+			time.Sleep(500 * time.Millisecond)
+		}
+		synctest.Wait()
+
+		assert.Equal(t, 3, mock.listCallCount(), "should have resynced at 5s and 10s")
+
+		cancel()
+		<-errCh
+	})
+}
+
+// flakyInspectClient fails the first few inspects of each container before
+// delegating to the mock.
+type flakyInspectClient struct {
+	*mockDockerClient
+	failures map[string]int
+}
+
+func (f *flakyInspectClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	f.mu.Lock()
+	if f.failures[containerID] > 0 {
+		f.failures[containerID]--
+		f.inspected = append(f.inspected, containerID)
+		f.mu.Unlock()
+		return container.InspectResponse{}, fmt.Errorf("connection reset")
+	}
+	f.mu.Unlock()
+	return f.mockDockerClient.ContainerInspect(ctx, containerID)
+}
+
+func TestRun_WithInspectRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		want    []string
+	}{
+		{name: "without retries the container is skipped", retries: 0, want: []string{"container2"}},
+		{name: "with retries the container is included", retries: 2, want: []string{"container1", "container2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				mock := newMockDockerClient()
+				mock.setContainers(
+					container.InspectResponse{
+						ContainerJSONBase: &container.ContainerJSONBase{
+							ID:    "container1",
+							State: &container.State{Status: "running"},
+						},
+						Config: &container.Config{},
+					},
+					container.InspectResponse{
+						ContainerJSONBase: &container.ContainerJSONBase{
+							ID:    "container2",
+							State: &container.State{Status: "running"},
+						},
+						Config: &container.Config{},
+					},
+				)
+				client := &flakyInspectClient{mockDockerClient: mock, failures: map[string]int{"container1": 1}}
+
+				var ids []string
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, func(containers []Container) {
+						for _, c := range containers {
+							ids = append(ids, c.ID)
+						}
+					}, WithDockerClient(client), WithInspectRetries(tt.retries))
+				}()
+
+				time.Sleep(time.Second)
+				synctest.Wait()
+
+				cancel()
+				<-errCh
+
+				assert.Equal(t, tt.want, ids)
+			})
+		})
+	}
+}
+
+func TestRun_WithInspectRetries_NotFoundIsNotRetried(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.summaries = []container.Summary{{ID: "missing"}}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock), WithInspectRetries(3))
+		}()
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		assert.Equal(t, 1, mock.inspectCount())
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithEventGapThreshold(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Second),
+				WithMaxDebounceTime(time.Minute),
+				WithEventGapThreshold(5*time.Second),
+			)
+		}()
+
+		synctest.Wait()
+		assert.Equal(t, 1, mock.listCallCount())
+
+		// A prompt event is debounced as normal.
+		mock.eventCh <- events.Message{
+			Type:     events.ContainerEventType,
+			Action:   events.ActionStart,
+			Actor:    events.Actor{ID: "c1"},
+			TimeNano: time.Now().Add(-time.Second).UnixNano(),
+		}
+		synctest.Wait()
+		assert.Equal(t, 1, mock.listCallCount(), "prompt event should be debounced")
+
+		// An event that arrives long after it occurred suggests the stream
+		// stalled, so a refresh happens immediately.
+		mock.eventCh <- events.Message{
+			Type:     events.ContainerEventType,
+			Action:   events.ActionStart,
+			Actor:    events.Actor{ID: "c1"},
+			TimeNano: time.Now().Add(-time.Minute).UnixNano(),
+		}
+		synctest.Wait()
+		assert.Equal(t, 2, mock.listCallCount(), "late event should trigger an immediate refresh")
+
+		// The pending debounce was satisfied by the refresh.
+		time.Sleep(20 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, 2, mock.listCallCount())
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestMonitor_LastEventTime(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		m := New(func([]Container) {}, WithDockerClient(mock), WithMaxIdleTime(10*time.Second))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+
+		synctest.Wait()
+		assert.True(t, m.LastEventTime().IsZero())
+
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "c1"}}
+		synctest.Wait()
+		received := time.Now()
+		assert.Equal(t, received, m.LastEventTime())
+
+		// When the stream goes quiet, containers are still refreshed after the
+		// idle time, and the last event time shows how long it has been quiet.
+		time.Sleep(15 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, 3, mock.listCallCount())
+		assert.Equal(t, 15*time.Second, time.Since(m.LastEventTime()))
+
+		assert.NoError(t, m.Stop(context.Background()))
+		assert.NoError(t, <-errCh)
+	})
+}
+
+func TestMonitor_LastCallbackTimeAndCurrentCount(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.listBlock = make(chan struct{})
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+
+		m := New(func([]Container) {}, WithDockerClient(mock), WithDebounce(10*time.Millisecond))
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(context.Background())
+		}()
+
+		synctest.Wait()
+		assert.True(t, m.LastCallbackTime().IsZero())
+		assert.Equal(t, 0, m.CurrentCount())
+
+		close(mock.listBlock)
+		synctest.Wait()
+		first := time.Now()
+		assert.Equal(t, first, m.LastCallbackTime())
+		assert.Equal(t, 1, m.CurrentCount())
+
+		time.Sleep(time.Second)
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "container2"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+		second := m.LastCallbackTime()
+		assert.Equal(t, first.Add(time.Second+10*time.Millisecond), second)
+		assert.Equal(t, 2, m.CurrentCount())
+
+		// Unchanged containers don't invoke the callback, so nothing is updated.
+		assert.NoError(t, m.RefreshNow())
+		assert.Equal(t, second, m.LastCallbackTime())
+		assert.Equal(t, 2, m.CurrentCount())
+
+		assert.NoError(t, m.Stop(context.Background()))
+		assert.NoError(t, <-errCh)
+	})
+}
+
+func TestRun_WithStartupTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.listBlock = make(chan struct{})
+
+		called := false
+		start := time.Now()
+		err := Run(context.Background(), func([]Container) {
+			called = true
+		}, WithDockerClient(mock), WithStartupTimeout(5*time.Second))
+
+		assert.ErrorIs(t, err, ErrStartupTimeout)
+		assert.Equal(t, 5*time.Second, time.Since(start))
+		assert.False(t, called)
+	})
+}
+
+func TestRun_WithStartupTimeout_IncludesReconnects(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.listErr = fmt.Errorf("daemon unavailable")
+
+		start := time.Now()
+		err := Run(context.Background(), func([]Container) {},
+			WithDockerClient(mock),
+			WithAutoReconnect(time.Second, time.Second, 0),
+			WithStartupTimeout(5*time.Second),
+		)
+
+		assert.ErrorIs(t, err, ErrStartupTimeout)
+		assert.Equal(t, 5*time.Second, time.Since(start))
+		assert.Greater(t, mock.listCallCount(), 1, "should have retried before timing out")
+	})
+}
+
+func TestRun_WithStartupTimeout_NotAppliedAfterStartup(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock), WithStartupTimeout(5*time.Second))
+		}()
+
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+
+		select {
+		case err := <-errCh:
+			t.Fatalf("Run returned unexpectedly: %v", err)
+		default:
+		}
+
+		cancel()
+		err := <-errCh
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, ErrStartupTimeout)
+	})
+}
+
+func TestRun_MaxRetriesExceeded(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+		for range 3 {
+			mock.errCh <- errors.New("connection reset")
+		}
+
+		err := Run(context.Background(), func([]Container) {},
+			WithDockerClient(mock),
+			WithAutoReconnect(time.Second, time.Second, 2),
+		)
+
+		assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+		assert.ErrorContains(t, err, "connection reset")
+		assert.Equal(t, 3, mock.eventCallCount())
+	})
+}
+
+func TestRun_MaxRetriesExceededByCleanClose(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+		for range 3 {
+			mock.errCh <- nil
+		}
+
+		err := Run(context.Background(), func([]Container) {},
+			WithDockerClient(mock),
+			WithAutoReconnect(time.Second, time.Second, 2),
+		)
+
+		assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+		assert.Equal(t, 3, mock.eventCallCount())
+	})
+}
+
+func TestRun_WatcherErrorWithoutReconnectIsNotMaxRetries(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers()
+		mock.errCh <- errors.New("connection reset")
+
+		err := Run(context.Background(), func([]Container) {}, WithDockerClient(mock))
+
+		assert.ErrorContains(t, err, "connection reset")
+		assert.NotErrorIs(t, err, ErrMaxRetriesExceeded)
+	})
+}
+
+func TestRun_WithEventCallback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		withState := func(state string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: state},
+				},
+				Config: &container.Config{},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(withState("running"))
+
+		var mu sync.Mutex
+		var actions [][]string
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, nil,
+				WithDockerClient(mock),
+				WithDebounce(time.Second),
+				WithEventCallback(func(a []string, containers []Container) {
+					mu.Lock()
+					actions = append(actions, a)
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		synctest.Wait()
+
+		// Several events within the debounce window are reported together.
+		mock.setContainers(withState("exited"))
+		for _, action := range []events.Action{events.ActionKill, events.ActionDie, events.ActionStop, events.ActionDie} {
+			mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: action, Actor: events.Actor{ID: "container1"}}
+			time.Sleep(100 * time.Millisecond)
+		}
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		mock.setContainers(withState("running"))
+		mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "container1"}}
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, [][]string{
+			nil,
+			{"kill", "die", "stop"},
+			{"start"},
+		}, actions)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithMinCallbackInterval(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		setName := func(mock *mockDockerClient, name string) {
+			mock.setContainers(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/" + name,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			})
+		}
+
+		mock := newMockDockerClient()
+		setName(mock, "v0")
+
+		var mu sync.Mutex
+		var times []time.Time
+		var names []string
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				times = append(times, time.Now())
+				names = append(names, containers[0].Name)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithMinCallbackInterval(time.Second),
+			)
+		}()
+
+		synctest.Wait()
+
+		// Change the container every 100ms for 3 seconds.
+		for i := 1; i <= 30; i++ {
+			setName(mock, fmt.Sprintf("v%d", i))
+			mock.eventCh <- events.Message{Type: events.ContainerEventType, Action: events.ActionRename, Actor: events.Actor{ID: "container1"}}
+			time.Sleep(100 * time.Millisecond)
+		}
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Len(t, times, 4, "should be limited to one callback per second")
+		for i := 1; i < len(times); i++ {
+			assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), time.Second)
+		}
+		assert.Equal(t, "v30", names[len(names)-1], "latest state should be delivered")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithMinCallbackInterval_AfterReconnect(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		setName := func(mock *mockDockerClient, name string) {
+			mock.setContainers(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/" + name,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			})
+		}
+
+		mock := newMockDockerClient()
+		setName(mock, "v0")
+
+		var mu sync.Mutex
+		var names []string
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				names = append(names, containers[0].Name)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithMinCallbackInterval(5*time.Second),
+				WithAutoReconnect(time.Second, time.Second, 0),
+			)
+		}()
+
+		synctest.Wait()
+
+		// The initial gather after reconnecting happens within the minimum
+		// interval, so has to be delayed rather than dropped.
+		setName(mock, "v1")
+		mock.errCh <- nil
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, []string{"v0", "v1"}, names)
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithSeedState(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		seed, err := Snapshot(ctx, WithDockerClient(mock))
+		assert.NoError(t, err)
+
+		var calls [][]Container
+		var removedCalls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithSeedState(seed),
+				WithRemovedCallback(func(containers []Container) {
+					mu.Lock()
+					removedCalls = append(removedCalls, containers)
+					mu.Unlock()
+				}),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Empty(t, calls, "callback should not be called when state matches the seed")
+		mu.Unlock()
+
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					Name:  "/test2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "redis:latest"},
+			},
+		)
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, calls, 1, "callback should be called when state changes") {
+			assert.Len(t, calls[0], 1)
+			assert.Equal(t, "container2", calls[0][0].ID)
+		}
+		if assert.Len(t, removedCalls, 1, "seeded containers should be reported as removed") {
+			assert.Equal(t, "container1", removedCalls[0][0].ID)
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithSeedFingerprint(t *testing.T) {
+	newMock := func() *mockDockerClient {
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+		return mock
+	}
+
+	seed, err := Snapshot(context.Background(), WithDockerClient(newMock()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint uint64
+		wantCalls   int
+	}{
+		{name: "matching", fingerprint: Fingerprint(seed), wantCalls: 0},
+		{name: "differing", fingerprint: Fingerprint(nil), wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				calls := 0
+				mu := sync.Mutex{}
+
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, func([]Container) {
+						mu.Lock()
+						calls++
+						mu.Unlock()
+					},
+						WithDockerClient(newMock()),
+						WithSeedFingerprint(tt.fingerprint),
+					)
+				}()
+
+				time.Sleep(50 * time.Millisecond)
+				synctest.Wait()
+
+				mu.Lock()
+				assert.Equal(t, tt.wantCalls, calls)
+				mu.Unlock()
+
+				cancel()
+				<-errCh
+			})
+		})
+	}
+}
+
+func TestRun_WithSeedState_Differs(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+		)
+
+		seed := []Container{{ID: "container1", Name: "test1", Image: "nginx:latest", State: "exited"}}
+
+		callCount := 0
+		mu := sync.Mutex{}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {
+				mu.Lock()
+				callCount++
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithSeedState(seed),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Equal(t, 1, callCount, "callback should be called when state differs from the seed")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithPauseOnError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		monitor := New(func(containers []Container) {
+			mu.Lock()
+			calls = append(calls, containers)
+			mu.Unlock()
+		},
+			WithDockerClient(mock),
+			WithDebounce(10*time.Millisecond),
+			WithPauseOnError(),
+		)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- monitor.Start(ctx)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.mu.Lock()
+		mock.listErr = fmt.Errorf("daemon unavailable")
+		mock.mu.Unlock()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		assert.ErrorContains(t, monitor.RefreshNow(), "daemon unavailable")
+
+		select {
+		case err := <-errCh:
+			t.Fatalf("monitor should keep running, but returned %v", err)
+		default:
+		}
+
+		mu.Lock()
+		if assert.Len(t, calls, 1, "callback should not be invoked while refreshes are failing") {
+			assert.Equal(t, "container1", calls[0][0].ID)
+		}
+		mu.Unlock()
+
+		mock.mu.Lock()
+		mock.listErr = nil
+		mock.mu.Unlock()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, calls, 2, "callback should be invoked once refreshes succeed") {
+			assert.Equal(t, "container2", calls[1][0].ID)
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithPauseOnError_InspectFailure(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+		client := &flakyInspectClient{mockDockerClient: mock, failures: map[string]int{}}
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(client),
+				WithDebounce(10*time.Millisecond),
+				WithPauseOnError(),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		client.mu.Lock()
+		client.failures["container1"] = 1
+		client.mu.Unlock()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "update"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Len(t, calls, 1, "a failed inspection should not cause the container to be removed")
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithStaleOnListError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			},
+		)
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithStaleOnListError(time.Minute),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// Transient failure within the staleness window
+		mock.mu.Lock()
+		mock.listErr = fmt.Errorf("daemon unavailable")
+		mock.mu.Unlock()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		select {
+		case err := <-errCh:
+			t.Fatalf("monitor should keep running, but returned %v", err)
+		default:
+		}
+
+		mu.Lock()
+		assert.Len(t, calls, 1, "callback should not be invoked while listing is failing")
+		mu.Unlock()
+
+		mock.mu.Lock()
+		mock.listErr = nil
+		mock.mu.Unlock()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		assert.Len(t, calls, 1, "unchanged state should not invoke callback after recovering")
+		mu.Unlock()
+
+		// Failure that outlasts the staleness window
+		mock.mu.Lock()
+		mock.listErr = fmt.Errorf("daemon unavailable")
+		mock.mu.Unlock()
+
+		time.Sleep(2 * time.Minute)
+		synctest.Wait()
+
+		select {
+		case err := <-errCh:
+			assert.ErrorContains(t, err, "daemon unavailable")
+		default:
+			t.Fatal("monitor should return an error once the state is too stale")
+		}
+	})
+}
+
+func TestRun_WithPerContainerDebounce(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(id, version string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Labels: map[string]string{"version": version}},
+			}
+		}
+		versions := func(containers []Container) map[string]string {
+			result := make(map[string]string)
+			for _, c := range containers {
+				result[c.ID] = c.Labels["version"]
+			}
+			return result
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(newContainer("noisy", "0"), newContainer("stable", "1"))
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithPerContainerDebounce(500*time.Millisecond),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		// The noisy container changes every 200ms for 2s, while the stable
+		// container changes once near the start.
+		for i := 1; i <= 10; i++ {
+			stable := "1"
+			if i > 1 {
+				stable = "2"
+			}
+			mock.setContainers(newContainer("noisy", fmt.Sprint(i)), newContainer("stable", stable))
+			mock.eventCh <- events.Message{Type: "container", Action: "update", Actor: events.Actor{ID: "noisy"}}
+			if i == 2 {
+				mock.eventCh <- events.Message{Type: "container", Action: "update", Actor: events.Actor{ID: "stable"}}
+			}
+			time.Sleep(200 * time.Millisecond)
+			synctest.Wait()
+
+			if i == 5 {
+				mu.Lock()
+				if assert.Len(t, calls, 2, "stable container should be updated once settled") {
+					assert.Equal(t, map[string]string{"noisy": "0", "stable": "2"}, versions(calls[1]))
+				}
+				mu.Unlock()
+			}
+		}
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, calls, 3, "noisy container should be updated once settled") {
+			assert.Equal(t, map[string]string{"noisy": "10", "stable": "2"}, versions(calls[2]))
+		}
+		mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithMaxContainers(t *testing.T) {
+	newMock := func() *mockDockerClient {
+		mock := newMockDockerClient()
+		var inspects []container.InspectResponse
+		for _, id := range []string{"e", "c", "a", "d", "b"} {
+			inspects = append(inspects, container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			})
+		}
+		mock.setContainers(inspects...)
+		return mock
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mock := newMock()
+
+			var ids []string
+			mu := sync.Mutex{}
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- Run(ctx, func(containers []Container) {
+					mu.Lock()
+					defer mu.Unlock()
+					ids = nil
+					for _, c := range containers {
+						ids = append(ids, c.ID)
+					}
+				},
+					WithDockerClient(mock),
+					WithMaxContainers(3, ContainerLimitTruncate),
+				)
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+			synctest.Wait()
+
+			mu.Lock()
+			assert.Equal(t, []string{"a", "b", "c"}, ids)
+			mu.Unlock()
+			assert.Equal(t, 3, mock.inspectCount())
+
+			cancel()
+			<-errCh
+		})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			mock := newMock()
+
+			err := Run(context.Background(), func([]Container) {
+				t.Error("callback should not be invoked")
+			},
+				WithDockerClient(mock),
+				WithMaxContainers(3, ContainerLimitError),
+			)
+			assert.ErrorIs(t, err, ErrTooManyContainers)
+			assert.Equal(t, 0, mock.inspectCount())
+		})
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			mock := newMock()
+
+			containers, err := Snapshot(context.Background(),
+				WithDockerClient(mock),
+				WithMaxContainers(5, ContainerLimitError),
+			)
+			assert.NoError(t, err)
+			assert.Len(t, containers, 5)
+		})
+	})
+}
+
+func TestRun_WithSummaryOnly(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		mock := newMockDockerClient()
+		mock.summaries = []container.Summary{
+			{
+				ID:      "container1",
+				Names:   []string{"/web"},
+				Image:   "nginx:latest",
+				ImageID: "sha256:abc",
+				Created: created.Unix(),
+				Labels:  map[string]string{"app": "web"},
+				State:   container.StateRunning,
+			},
+			{
+				ID:    "container2",
+				Names: []string{"/db"},
+				Image: "postgres:latest",
+				State: container.StateExited,
+			},
+		}
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithFilter(StateEquals(StateRunning)),
+				WithSummaryOnly(),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start", Actor: events.Actor{ID: "container2"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, calls, 1) {
+			assert.Equal(t, []Container{{
+				ID:      "container1",
+				Name:    "web",
+				Image:   "nginx:latest",
+				ImageID: "sha256:abc",
+				State:   "running",
+				Created: created,
+				Labels:  map[string]string{"app": "web"},
+			}}, calls[0])
+		}
+		mu.Unlock()
+
+		assert.Equal(t, 2, mock.listCallCount())
+		assert.Equal(t, 0, mock.inspectCount(), "containers should not be inspected")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithSummaryOnly_WatchIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+	}{
+		{name: "full ID", ids: []string{"3f2a9c81d7e4"}, want: []string{"3f2a9c81d7e4"}},
+		{name: "abbreviated ID", ids: []string{"8b41"}, want: []string{"8b41e07c5a92"}},
+		{name: "name", ids: []string{"web"}, want: []string{"3f2a9c81d7e4"}},
+		{name: "name with leading slash", ids: []string{"/db"}, want: []string{"8b41e07c5a92"}},
+		{name: "unknown", ids: []string{"cache"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockDockerClient()
+			mock.summaries = []container.Summary{
+				{ID: "3f2a9c81d7e4", Names: []string{"/web"}, State: container.StateRunning},
+				{ID: "8b41e07c5a92", Names: []string{"/db"}, State: container.StateRunning},
+			}
+
+			containers, err := Snapshot(context.Background(), WithDockerClient(mock), WithSummaryOnly(), WithWatchIDs(tt.ids...))
+			assert.NoError(t, err)
+
+			var ids []string
+			for _, c := range containers {
+				ids = append(ids, c.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestRun_WithDebounceStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy DebounceStrategy
+		want     []time.Duration
+	}{
+		{
+			name:     "trailing refreshes once events stop",
+			strategy: DebounceTrailing,
+			want:     []time.Duration{700 * time.Millisecond},
+		},
+		{
+			name:     "leading refreshes immediately and after events stop",
+			strategy: DebounceLeading,
+			want:     []time.Duration{0, 700 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				mock := newMockDockerClient()
+				mock.setContainers()
+
+				var callbacks []time.Duration
+				var start time.Time
+				mu := sync.Mutex{}
+
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, func([]Container) {},
+						WithDockerClient(mock),
+						WithDebounce(500*time.Millisecond),
+						WithDebounceStrategy(tt.strategy),
+						WithEventCallback(func(actions []string, _ []Container) {
+							if len(actions) > 0 {
+								mu.Lock()
+								callbacks = append(callbacks, time.Since(start))
+								mu.Unlock()
+							}
+						}),
+					)
+				}()
+
+				synctest.Wait()
+				start = time.Now()
+
+				// Each event changes the containers, so that every refresh invokes the callback.
+				for i := range 3 {
+					mock.setContainers(container.InspectResponse{
+						ContainerJSONBase: &container.ContainerJSONBase{
+							ID:    fmt.Sprintf("container%d", i),
+							State: &container.State{Status: "running"},
+						},
+						Config: &container.Config{},
+					})
+					mock.eventCh <- events.Message{Type: "container", Action: "start"}
+					synctest.Wait()
+					time.Sleep(100 * time.Millisecond)
+				}
+
+				time.Sleep(time.Second)
+				synctest.Wait()
+
+				mu.Lock()
+				assert.Equal(t, tt.want, callbacks)
+				mu.Unlock()
+
+				cancel()
+				<-errCh
+			})
+		})
+	}
+}
+
+func TestRun_WithDebounceStrategy_LeadingSingleEvent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {},
+				WithDockerClient(mock),
+				WithDebounce(500*time.Millisecond),
+				WithDebounceStrategy(DebounceLeading),
+			)
+		}()
+
+		synctest.Wait()
+		assert.Equal(t, 1, mock.listCallCount())
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		synctest.Wait()
+		assert.Equal(t, 2, mock.listCallCount(), "first event should refresh immediately")
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		assert.Equal(t, 2, mock.listCallCount(), "should not refresh again without further events")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithInspectCache(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		for i := 1; i <= 3; i++ {
+			id := fmt.Sprintf("container%d", i)
+			mock.summaries = append(mock.summaries, container.Summary{ID: id})
+			mock.inspects[id] = container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			}
+		}
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		monitor := New(func(containers []Container) {
+			mu.Lock()
+			calls = append(calls, containers)
+			mu.Unlock()
+		},
+			WithDockerClient(mock),
+			WithDebounce(10*time.Millisecond),
+			WithInspectCache(),
+		)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- monitor.Start(ctx)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 3, mock.inspectCount())
+
+		mock.mu.Lock()
+		mock.inspects["container2"].State.Status = "exited"
+		mock.mu.Unlock()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "die", Actor: events.Actor{ID: "container2"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 4, mock.inspectCount(), "only the container in the event should be inspected")
+
+		mu.Lock()
+		if assert.Len(t, calls, 2) {
+			assert.Equal(t, "exited", calls[1][1].State)
+		}
+		mu.Unlock()
+
+		mock.eventCh <- events.Message{Type: "network", Action: "connect", Actor: events.Actor{ID: "net1", Attributes: map[string]string{"container": "container3"}}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 5, mock.inspectCount(), "the container in a network event should be inspected")
+
+		mock.eventCh <- events.Message{Type: "container", Action: "start"}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 8, mock.inspectCount(), "events without an actor should inspect everything")
+
+		assert.NoError(t, monitor.RefreshNow())
+		assert.Equal(t, 11, mock.inspectCount(), "manual refreshes should inspect everything")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithoutInspectCache(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.summaries = []container.Summary{{ID: "container1"}, {ID: "container2"}}
+		for _, id := range []string{"container1", "container2"} {
+			mock.inspects[id] = container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    id,
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+			}
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func([]Container) {}, WithDockerClient(mock), WithDebounce(10*time.Millisecond))
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.eventCh <- events.Message{Type: "container", Action: "die", Actor: events.Actor{ID: "container2"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 4, mock.inspectCount(), "all containers should be inspected on every refresh")
+
+		cancel()
+		<-errCh
+	})
+}
+
+// networkInspectClient extends mockDockerClient to implement NetworkInspector.
+type networkInspectClient struct {
+	*mockDockerClient
+	networks  map[string]network.Inspect
+	inspected []string
+}
+
+func (n *networkInspectClient) NetworkInspect(_ context.Context, networkID string, _ network.InspectOptions) (network.Inspect, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.inspected = append(n.inspected, networkID)
+	if inspect, ok := n.networks[networkID]; ok {
+		return inspect, nil
+	}
+	return network.Inspect{}, fmt.Errorf("network not found: %s: %w", networkID, cerrdefs.ErrNotFound)
+}
+
+func TestRun_WithSizes(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest"},
+		})
+		mock.summaries[0].SizeRw = 1024
+		mock.summaries[0].SizeRootFs = 4096
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			},
+				WithDockerClient(mock),
+				WithDebounce(10*time.Millisecond),
+				WithSizes(),
+			)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mu.Lock()
+		if assert.Len(t, calls, 1) && assert.Len(t, calls[0], 1) {
+			assert.Equal(t, int64(1024), calls[0][0].SizeRw)
+			assert.Equal(t, int64(4096), calls[0][0].SizeRootFs)
+		}
+		mu.Unlock()
+
+		mock.mu.Lock()
+		if assert.Len(t, mock.listOpts, 1) {
+			assert.True(t, mock.listOpts[0].Size)
+		}
+		mock.mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithNetworkLabels(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+				NetworkSettings: &container.NetworkSettings{
+					Networks: map[string]*network.EndpointSettings{
+						"web":  {NetworkID: "net1"},
+						"gone": {NetworkID: "net2"},
+					},
+				},
+			},
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container2",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{},
+				NetworkSettings: &container.NetworkSettings{
+					Networks: map[string]*network.EndpointSettings{
+						"web": {NetworkID: "net1"},
+					},
+				},
+			},
+		)
+		client := &networkInspectClient{
+			mockDockerClient: mock,
+			networks: map[string]network.Inspect{
+				"net1": {ID: "net1", Labels: map[string]string{"tier": "frontend"}},
+			},
+		}
+
+		var received []Container
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				received = containers
+			}, WithDockerClient(client), WithNetworkLabels())
+		}()
+
+		synctest.Wait()
+
+		if assert.Len(t, received, 2) {
+			for _, c := range received {
+				for _, n := range c.Networks {
+					if n.ID == "net1" {
+						assert.Equal(t, map[string]string{"tier": "frontend"}, n.Labels)
+					} else {
+						assert.Nil(t, n.Labels, "networks that don't exist should have no labels")
+					}
+				}
+			}
+		}
+		assert.ElementsMatch(t, []string{"net1", "net2"}, client.inspected, "each network should be inspected once per refresh")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithNetworkLabels_RequiresNetworkInspector(t *testing.T) {
+	err := Run(context.Background(), func([]Container) {}, WithDockerClient(newMockDockerClient()), WithNetworkLabels())
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestRun_RestartEvent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		newContainer := func(startedAt string) container.InspectResponse {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running", StartedAt: startedAt},
+				},
+				Config: &container.Config{Labels: map[string]string{"started": startedAt}},
+			}
+		}
+
+		mock := newMockDockerClient()
+		mock.setContainers(newContainer("1"))
+
+		var calls [][]Container
+		mu := sync.Mutex{}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, func(containers []Container) {
+				mu.Lock()
+				calls = append(calls, containers)
+				mu.Unlock()
+			}, WithDockerClient(mock), WithDebounce(10*time.Millisecond))
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		mock.setContainers(newContainer("2"))
+		mock.eventCh <- events.Message{Type: "container", Action: "restart", Actor: events.Actor{ID: "container1"}}
+		time.Sleep(50 * time.Millisecond)
+		synctest.Wait()
+
+		assert.Equal(t, 2, mock.listCallCount(), "restart event should trigger a refresh")
+		mu.Lock()
+		if assert.Len(t, calls, 2) {
+			assert.Equal(t, "2", calls[1][0].Labels["started"])
+		}
+		mu.Unlock()
+
+		mock.mu.Lock()
+		assert.Contains(t, mock.eventOpts[0].Filters.Get("event"), "restart")
+		mock.mu.Unlock()
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestRun_WithWatchedActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    []string
+	}{
+		{
+			name: "default actions",
+			want: defaultActions,
+		},
+		{
+			name:    "watched actions",
+			options: []Option{WithWatchedActions("start", "stop", "die")},
+			want:    []string{"start", "stop", "die"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				mock := newMockDockerClient()
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- Run(ctx, func([]Container) {}, append(tt.options, WithDockerClient(mock))...)
+				}()
+
+				synctest.Wait()
+
+				mock.mu.Lock()
+				if assert.Len(t, mock.eventOpts, 1) {
+					args := mock.eventOpts[0].Filters
+					assert.ElementsMatch(t, []string{"container", "network"}, args.Get("type"))
+					assert.ElementsMatch(t, tt.want, args.Get("event"))
+				}
+				mock.mu.Unlock()
+
+				cancel()
+				<-errCh
+			})
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package containuum
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// filterInfo describes a filter created by this package, and evaluates it
+// within a scope.
+type filterInfo struct {
+	match    func(*filterScope, Container) bool
+	name     string
+	args     []any
+	children []Filter
+}
+
+// filterInfos holds the filterInfo of each filter created by this package,
+// keyed by the address of the filter's closure (see filterKey), so that filters
+// can be described without being invoked. Entries are weak pointers, as the
+// info is only referenced by the filter itself, and are removed once the
+// filter has been garbage collected.
+var filterInfos sync.Map
+
+// filterKey returns the address of the closure backing the given filter. Each
+// filter created by register is a distinct closure, so this identifies it for
+// as long as it is alive.
+func filterKey(filter Filter) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&filter))
+}
+
+// register returns a filter that evaluates the given info outside of any scope,
+// and records the info so it can be found with lookup.
+func register(info *filterInfo) Filter {
+	filter := func(c Container) bool {
+		return info.match(nil, c)
+	}
+
+	key := filterKey(filter)
+	ptr := weak.Make(info)
+	filterInfos.Store(key, ptr)
+	runtime.AddCleanup(info, func(key uintptr) {
+		filterInfos.CompareAndDelete(key, ptr)
+	}, key)
+	return filter
+}
+
+// lookup returns the info of a filter created by this package, or nil if the
+// filter was created elsewhere.
+func lookup(filter Filter) *filterInfo {
+	if filter == nil {
+		return nil
+	}
+	ptr, ok := filterInfos.Load(filterKey(filter))
+	if !ok {
+		return nil
+	}
+	return ptr.(weak.Pointer[filterInfo]).Value()
+}
+
+// described returns a filter that evaluates the given function, and is
+// described with the given name and arguments.
+func described(match func(Container) bool, name string, args ...any) Filter {
+	return register(&filterInfo{
+		match: func(_ *filterScope, c Container) bool { return match(c) },
+		name:  name,
+		args:  args,
+	})
+}
+
+// combined returns a filter that evaluates the given function, and is
+// described with the given name and the descriptions of the child filters.
+// The function is given the scope it is evaluated in, so it can pass it on to
+// the child filters.
+func combined(match func(*filterScope, Container) bool, name string, children ...Filter) Filter {
+	return register(&filterInfo{match: match, name: name, children: children})
+}
+
+// Describe returns a human-readable description of the given filter, such as
+// `All(StateEquals("running"), Not(LabelExists("x")))`. Filters that were not
+// created by this package are described as "Custom", and are never invoked.
+func Describe(filter Filter) string {
+	if filter == nil {
+		return "None"
+	}
+
+	sb := &strings.Builder{}
+	describe(sb, filter)
+	return sb.String()
+}
+
+// describe writes the description of a single filter.
+func describe(sb *strings.Builder, filter Filter) {
+	info := lookup(filter)
+	if info == nil {
+		sb.WriteString("Custom")
+		return
+	}
+
+	sb.WriteString(info.name)
+	sb.WriteByte('(')
+	for i, arg := range info.args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if s, ok := arg.(string); ok {
+			sb.WriteString(fmt.Sprintf("%q", s))
+		} else {
+			sb.WriteString(fmt.Sprint(arg))
+		}
+	}
+	for i, child := range info.children {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		describe(sb, child)
+	}
+	sb.WriteByte(')')
+}
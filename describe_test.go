@@ -0,0 +1,98 @@
+package containuum
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{
+			name:   "nil filter",
+			filter: nil,
+			want:   "None",
+		},
+		{
+			name:   "single leaf",
+			filter: StateEquals("running"),
+			want:   `StateEquals("running")`,
+		},
+		{
+			name:   "multiple arguments",
+			filter: LabelEquals("env", "prod"),
+			want:   `LabelEquals("env", "prod")`,
+		},
+		{
+			name:   "variadic arguments",
+			filter: LabelValueIn("env", "prod", "staging"),
+			want:   `LabelValueIn("env", "prod", "staging")`,
+		},
+		{
+			name:   "nested combinators",
+			filter: All(StateEquals("running"), Not(LabelExists("x"))),
+			want:   `All(StateEquals("running"), Not(LabelExists("x")))`,
+		},
+		{
+			name:   "empty combinator",
+			filter: Any(),
+			want:   "Any()",
+		},
+		{
+			name:   "memoized filter",
+			filter: Memoize(StateEquals("running")),
+			want:   `Memoize(StateEquals("running"))`,
+		},
+		{
+			name:   "custom filter",
+			filter: func(Container) bool { return true },
+			want:   "Custom",
+		},
+		{
+			name:   "custom filter inside combinator",
+			filter: Any(func(Container) bool { return true }, StateEquals("exited")),
+			want:   `Any(Custom, StateEquals("exited"))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Describe(tt.filter))
+		})
+	}
+}
+
+func TestDescribe_DoesNotInvokeCustomFilters(t *testing.T) {
+	filter := All(StateEquals("running"), func(Container) bool {
+		panic("custom filter invoked")
+	})
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, `All(StateEquals("running"), Custom)`, Describe(filter))
+	})
+}
+
+func TestDescribe_SurvivesGarbageCollection(t *testing.T) {
+	filter := All(StateEquals("running"), Not(LabelExists("x")))
+	runtime.GC()
+	runtime.GC()
+
+	assert.Equal(t, `All(StateEquals("running"), Not(LabelExists("x")))`, Describe(filter))
+	assert.True(t, filter(runningProdWeb))
+}
+
+func TestDescribe_ForgetsCollectedFilters(t *testing.T) {
+	key := filterKey(StateEquals("running"))
+
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		_, ok := filterInfos.Load(key)
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -39,6 +39,9 @@ func TestIntegration_Basic(t *testing.T) {
 			containuum.WithDebounce(50*time.Millisecond),
 			containuum.WithMaxDebounceTime(200*time.Millisecond),
 			containuum.WithMaxIdleTime(10*time.Second),
+			containuum.WithSort(func(a, b containuum.Container) bool {
+				return a.Name < b.Name
+			}),
 		)
 	}()
 
@@ -2,64 +2,312 @@ package containuum
 
 import (
 	"encoding/binary"
+	"hash"
 	"hash/fnv"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Container represents a Docker container's relevant state.
+//
+// Containers can be marshalled to and from JSON, using camelCase keys. Empty
+// slices and maps are omitted.
 type Container struct {
-	ID       string            // Full container ID
-	Name     string            // Container name (without leading slash)
-	Image    string            // Image name (e.g., "nginx:latest")
-	State    string            // Container state (e.g., "running", "exited", "paused")
-	Labels   map[string]string // Container labels
-	Networks []Network         // All connected networks
-	Ports    []Port            // Published port mappings
+	ID            string            `json:"id"`                   // Full container ID
+	Name          string            `json:"name"`                 // Container name (without leading slash)
+	Image         string            `json:"image"`                // Image name (e.g., "nginx:latest")
+	ImageID       string            `json:"imageId"`              // ID of the image the container was created from (e.g., "sha256:...")
+	State         string            `json:"state"`                // Container state (e.g., "running", "exited", "paused")
+	Created       time.Time         `json:"created,omitzero"`     // Time the container was created (zero if unknown)
+	Labels        map[string]string `json:"labels,omitempty"`     // Container labels
+	Networks      []Network         `json:"networks,omitempty"`   // All connected networks
+	Ports         []Port            `json:"ports,omitempty"`      // Published port mappings
+	StopSignal    string            `json:"stopSignal"`           // Signal sent to stop the container (empty if the default is used)
+	StopTimeout   int               `json:"stopTimeout"`          // Seconds to wait before killing the container (0 if the default is used)
+	PidMode       string            `json:"pidMode"`              // PID namespace mode (e.g., "host", "container:<id>", or empty for private)
+	IpcMode       string            `json:"ipcMode"`              // IPC namespace mode (e.g., "host", "shareable", "container:<id>")
+	RestartPolicy string            `json:"restartPolicy"`        // Restart policy name (e.g., "always", "unless-stopped", or empty for none)
+	Health        string            `json:"health"`               // Health status ("starting", "healthy", "unhealthy"), or empty if there is no health check
+	OOMKilled     bool              `json:"oomKilled"`            // Whether the container was last killed for running out of memory
+	LogPath       string            `json:"logPath,omitempty"`    // Path on the host of the container's log file (empty if the log driver doesn't use one)
+	Pid           int               `json:"pid,omitempty"`        // Host PID of the container's main process (0 if not running); not used when deduplicating
+	SizeRw        int64             `json:"sizeRw,omitempty"`     // Size in bytes of the container's writable layer (only populated with WithSizes)
+	SizeRootFs    int64             `json:"sizeRootFs,omitempty"` // Total size in bytes of the container's filesystem (only populated with WithSizes)
+
+	// Command is the command line the container was configured to run: the
+	// image or container's Config.Entrypoint followed by its Config.Cmd.
+	Command []string `json:"command,omitempty"`
+
+	// Swarm describes the swarm task the container is running, based on the
+	// labels applied by Docker Swarm. It is nil if the container isn't a task.
+	Swarm *SwarmInfo `json:"swarm,omitempty"`
+}
+
+// Summary returns a short description of the container, in the form
+// "name (image) [state]". If the container has no name, its ID is used instead.
+func (c Container) Summary() string {
+	var b strings.Builder
+	b.Grow(len(c.ID) + len(c.Name) + len(c.Image) + len(c.State) + 6)
+	c.writeSummary(&b)
+	return b.String()
+}
+
+// String returns a description of the container, in the form
+// "name (image) [state] 8080->80/tcp", listing any published ports as
+// host port -> container port in the same way as `docker ps`.
+func (c Container) String() string {
+	var b strings.Builder
+	b.Grow(len(c.ID) + len(c.Name) + len(c.Image) + len(c.State) + 6 + 16*len(c.Ports))
+	c.writeSummary(&b)
+
+	var buf [5]byte
+	for i := range c.Ports {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(", ")
+		}
+		b.Write(strconv.AppendUint(buf[:0], uint64(c.Ports[i].HostPort), 10))
+		b.WriteString("->")
+		b.Write(strconv.AppendUint(buf[:0], uint64(c.Ports[i].ContainerPort), 10))
+		b.WriteByte('/')
+		b.WriteString(c.Ports[i].Protocol)
+	}
+	return b.String()
+}
+
+// LabelsWithPrefix returns the container's labels whose keys start with the
+// given prefix. Keys are returned in full, including the prefix. Returns nil if
+// no labels match.
+func (c Container) LabelsWithPrefix(prefix string) map[string]string {
+	var result map[string]string
+	for k, v := range c.Labels {
+		if strings.HasPrefix(k, prefix) {
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Labels applied to containers by Docker Compose.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// ComposeProject returns the name of the Docker Compose project the container
+// belongs to, or "" if it wasn't created by Compose.
+func (c Container) ComposeProject() string {
+	return c.Labels[composeProjectLabel]
+}
+
+// ComposeService returns the name of the Docker Compose service the container
+// is running, or "" if it wasn't created by Compose.
+func (c Container) ComposeService() string {
+	return c.Labels[composeServiceLabel]
+}
+
+// writeSummary writes the container's summary to the builder.
+func (c *Container) writeSummary(b *strings.Builder) {
+	if c.Name != "" {
+		b.WriteString(c.Name)
+	} else {
+		b.WriteString(c.ID)
+	}
+	b.WriteString(" (")
+	b.WriteString(c.Image)
+	b.WriteString(") [")
+	b.WriteString(c.State)
+	b.WriteByte(']')
+}
+
+// Container states, as reported by Docker. State is a plain string so that
+// states added in future versions of Docker can still be represented; these
+// constants cover the states Docker currently reports.
+const (
+	StateCreated    = "created"
+	StateRunning    = "running"
+	StatePaused     = "paused"
+	StateRestarting = "restarting"
+	StateRemoving   = "removing"
+	StateExited     = "exited"
+	StateDead       = "dead"
+)
+
+// Field identifies a field of Container. Fields can be combined using bitwise OR.
+type Field uint64
+
+const (
+	FieldID Field = 1 << iota
+	FieldName
+	FieldImage
+	FieldState
+	FieldLabels
+	FieldNetworks
+	FieldPorts
+	FieldStopSignal
+	FieldStopTimeout
+	FieldPidMode
+	FieldIpcMode
+	FieldRestartPolicy
+	FieldCommand
+	FieldHealth
+	FieldOOMKilled
+	FieldSwarm
+	FieldImageID
+	FieldCreated
+	FieldSize
+	FieldLogPath
+
+	// AllFields includes every field of Container. Pid is never considered, as
+	// it changes whenever a container restarts.
+	AllFields Field = ^Field(0)
+)
+
+// hashOptions controls which parts of a Container contribute to its hash.
+type hashOptions struct {
+	fields        Field               // Fields to include
+	ignoredLabels map[string]struct{} // Label keys to exclude
 }
 
 // hash computes a hash of the Container.
 func (c *Container) hash() uint64 {
+	return c.hashWith(hashOptions{fields: AllFields})
+}
+
+// hashWith computes a hash of the Container, according to the given options.
+func (c *Container) hashWith(opts hashOptions) uint64 {
+	fields := opts.fields
 	h := fnv.New64a()
-	_, _ = h.Write([]byte(c.ID))
-	_, _ = h.Write([]byte(c.Name))
-	_, _ = h.Write([]byte(c.Image))
-	_, _ = h.Write([]byte(c.State))
+	if fields&FieldID != 0 {
+		_, _ = h.Write([]byte(c.ID))
+	}
+	if fields&FieldName != 0 {
+		_, _ = h.Write([]byte(c.Name))
+	}
+	if fields&FieldImage != 0 {
+		_, _ = h.Write([]byte(c.Image))
+	}
+	if fields&FieldImageID != 0 {
+		_, _ = h.Write([]byte(c.ImageID))
+	}
+	if fields&FieldState != 0 {
+		_, _ = h.Write([]byte(c.State))
+	}
+	if fields&FieldCreated != 0 {
+		_ = binary.Write(h, binary.LittleEndian, c.Created.Unix())
+		_ = binary.Write(h, binary.LittleEndian, int64(c.Created.Nanosecond()))
+	}
+	if fields&FieldSize != 0 {
+		_ = binary.Write(h, binary.LittleEndian, c.SizeRw)
+		_ = binary.Write(h, binary.LittleEndian, c.SizeRootFs)
+	}
+	if fields&FieldLogPath != 0 {
+		_, _ = h.Write([]byte(c.LogPath))
+	}
 
-	if len(c.Labels) > 0 {
+	if fields&FieldLabels != 0 && len(c.Labels) > 0 {
 		keys := make([]string, 0, len(c.Labels))
 		for k := range c.Labels {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
 		for _, k := range keys {
+			if _, ignored := opts.ignoredLabels[k]; ignored {
+				continue
+			}
 			_, _ = h.Write([]byte(k))
 			_, _ = h.Write([]byte(c.Labels[k]))
 		}
 	}
 
-	var networksHash uint64
-	for _, network := range c.Networks {
-		networksHash ^= network.hash()
+	if fields&FieldNetworks != 0 {
+		hashes := make([]uint64, len(c.Networks))
+		for i := range c.Networks {
+			hashes[i] = c.Networks[i].hash()
+		}
+		writeUnordered(h, hashes)
 	}
-	_ = binary.Write(h, binary.LittleEndian, networksHash)
 
-	var portsHash uint64
-	for _, port := range c.Ports {
-		portsHash ^= port.hash()
+	if fields&FieldPorts != 0 {
+		hashes := make([]uint64, len(c.Ports))
+		for i := range c.Ports {
+			hashes[i] = c.Ports[i].hash()
+		}
+		writeUnordered(h, hashes)
+	}
+
+	if fields&FieldStopSignal != 0 {
+		_, _ = h.Write([]byte(c.StopSignal))
+	}
+	if fields&FieldStopTimeout != 0 {
+		_ = binary.Write(h, binary.LittleEndian, int64(c.StopTimeout))
+	}
+	if fields&FieldPidMode != 0 {
+		_, _ = h.Write([]byte(c.PidMode))
+	}
+	if fields&FieldIpcMode != 0 {
+		_, _ = h.Write([]byte(c.IpcMode))
+	}
+	if fields&FieldRestartPolicy != 0 {
+		_, _ = h.Write([]byte(c.RestartPolicy))
+	}
+	if fields&FieldHealth != 0 {
+		_, _ = h.Write([]byte(c.Health))
+	}
+	if fields&FieldOOMKilled != 0 {
+		_ = binary.Write(h, binary.LittleEndian, c.OOMKilled)
+	}
+
+	if fields&FieldSwarm != 0 && c.Swarm != nil {
+		_, _ = h.Write([]byte{1})
+		_, _ = h.Write([]byte(c.Swarm.ServiceName))
+		_, _ = h.Write([]byte{0})
+		_ = binary.Write(h, binary.LittleEndian, int64(c.Swarm.TaskSlot))
+		_, _ = h.Write([]byte(c.Swarm.NodeID))
+	}
+
+	if fields&FieldCommand != 0 {
+		// Command order is significant, so each argument is written in turn
+		// with a terminator to avoid ambiguity between e.g. ["ab"] and ["a", "b"].
+		for _, arg := range c.Command {
+			_, _ = h.Write([]byte(arg))
+			_, _ = h.Write([]byte{0})
+		}
 	}
-	_ = binary.Write(h, binary.LittleEndian, portsHash)
 
 	return h.Sum64()
 }
 
+// writeUnordered writes the count and values of the given hashes to h in sorted
+// order, so the result doesn't depend on the order of the elements they were
+// computed from. Unlike combining them with XOR, duplicates don't cancel out.
+func writeUnordered(h hash.Hash64, hashes []uint64) {
+	slices.Sort(hashes)
+	_ = binary.Write(h, binary.LittleEndian, uint64(len(hashes)))
+	_ = binary.Write(h, binary.LittleEndian, hashes)
+}
+
 // Network represents a container's connection to a Docker network.
 type Network struct {
-	Name       string   // Network name
-	ID         string   // Network ID
-	IPAddress  string   // IPv4 address on this network
-	IP6Address string   // IPv6 address on this network (if any)
-	Gateway    string   // Gateway for this network
-	Aliases    []string // Container's DNS aliases on this network
+	Name         string            `json:"name"`                 // Network name
+	ID           string            `json:"id"`                   // Network ID
+	IPAddress    string            `json:"ipAddress"`            // IPv4 address on this network
+	IP6Address   string            `json:"ip6Address"`           // IPv6 address on this network (if any)
+	IPPrefixLen  int               `json:"ipPrefixLen"`          // Prefix length of the IPv4 subnet
+	IP6PrefixLen int               `json:"ip6PrefixLen"`         // Prefix length of the IPv6 subnet (0 if none)
+	Gateway      string            `json:"gateway"`              // Gateway for this network
+	Aliases      []string          `json:"aliases,omitempty"`    // Container's DNS aliases on this network
+	MacAddress   string            `json:"macAddress"`           // MAC address of the container's interface on this network
+	DriverOpts   map[string]string `json:"driverOpts,omitempty"` // Driver-specific options for the container's endpoint
+	Internal     bool              `json:"internal"`             // Whether the network appears to be internal (the container has an address but no gateway)
+	Labels       map[string]string `json:"labels,omitempty"`     // Labels of the network itself (only populated with WithNetworkLabels)
 }
 
 // hash computes a hash of the Network.
@@ -69,7 +317,11 @@ func (n *Network) hash() uint64 {
 	_, _ = h.Write([]byte(n.ID))
 	_, _ = h.Write([]byte(n.IPAddress))
 	_, _ = h.Write([]byte(n.IP6Address))
+	_ = binary.Write(h, binary.LittleEndian, int64(n.IPPrefixLen))
+	_ = binary.Write(h, binary.LittleEndian, int64(n.IP6PrefixLen))
 	_, _ = h.Write([]byte(n.Gateway))
+	_, _ = h.Write([]byte(n.MacAddress))
+	_ = binary.Write(h, binary.LittleEndian, n.Internal)
 
 	if len(n.Aliases) > 0 {
 		aliases := make([]string, len(n.Aliases))
@@ -80,15 +332,64 @@ func (n *Network) hash() uint64 {
 		}
 	}
 
+	if len(n.DriverOpts) > 0 {
+		keys := make([]string, 0, len(n.DriverOpts))
+		for k := range n.DriverOpts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, _ = h.Write([]byte(k))
+			_, _ = h.Write([]byte(n.DriverOpts[k]))
+		}
+	}
+
+	_ = binary.Write(h, binary.LittleEndian, uint64(len(n.Labels)))
+	if len(n.Labels) > 0 {
+		keys := make([]string, 0, len(n.Labels))
+		for k := range n.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, _ = h.Write([]byte(k))
+			_, _ = h.Write([]byte(n.Labels[k]))
+		}
+	}
+
 	return h.Sum64()
 }
 
+// completeness returns the number of populated fields in the Network.
+func (n *Network) completeness() int {
+	count := len(n.Aliases) + len(n.DriverOpts) + len(n.Labels)
+	for _, v := range []string{n.Name, n.ID, n.IPAddress, n.IP6Address, n.Gateway, n.MacAddress} {
+		if v != "" {
+			count++
+		}
+	}
+	if n.IPPrefixLen != 0 {
+		count++
+	}
+	if n.IP6PrefixLen != 0 {
+		count++
+	}
+	return count
+}
+
+// SwarmInfo describes the swarm task that a container is running.
+type SwarmInfo struct {
+	ServiceName string `json:"serviceName"` // Name of the swarm service
+	TaskSlot    int    `json:"taskSlot"`    // Slot of the task within a replicated service (0 for global services)
+	NodeID      string `json:"nodeId"`      // ID of the node the task was scheduled on
+}
+
 // Port represents a port mapping.
 type Port struct {
-	HostIP        string // Host IP (e.g., "0.0.0.0")
-	HostPort      uint16 // Port on host
-	ContainerPort uint16 // Port in container
-	Protocol      string // "tcp" or "udp"
+	HostIP        string `json:"hostIp"`        // Host IP (e.g., "0.0.0.0")
+	HostPort      uint16 `json:"hostPort"`      // Port on host
+	ContainerPort uint16 `json:"containerPort"` // Port in container
+	Protocol      string `json:"protocol"`      // "tcp", "udp" or "sctp"
 }
 
 // hash computes a hash of the Port.
@@ -100,3 +401,194 @@ func (p *Port) hash() uint64 {
 	_, _ = h.Write([]byte(p.Protocol))
 	return h.Sum64()
 }
+
+// ServicePort returns a function that determines the port a container's
+// service is listening on. If the container has the given label, it is parsed
+// as the port number. Otherwise, if exactly one container port is published
+// (possibly on several host addresses), that container port is used. If neither
+// is the case, or the label is not a valid port, false is returned.
+func ServicePort(labelKey string) func(Container) (uint16, bool) {
+	return func(c Container) (uint16, bool) {
+		if value, ok := c.Labels[labelKey]; ok {
+			port, err := strconv.ParseUint(value, 10, 16)
+			if err != nil || port == 0 {
+				return 0, false
+			}
+			return uint16(port), true
+		}
+
+		var port uint16
+		for i := range c.Ports {
+			if port != 0 && c.Ports[i].ContainerPort != port {
+				return 0, false
+			}
+			port = c.Ports[i].ContainerPort
+		}
+		return port, port != 0
+	}
+}
+
+// Equal reports whether the container has the same details as other. The order
+// of networks, aliases and ports is not significant. This is the comparison
+// used by Diff to determine whether a container has changed.
+func (c Container) Equal(other Container) bool {
+	return c.hash() == other.hash()
+}
+
+// Diff compares two sets of containers by ID, returning those only present in
+// current (added), those only present in previous (removed), and those present
+// in both whose details differ (changed, with their current details).
+func Diff(previous, current []Container) (added, removed, changed []Container) {
+	previousByID := make(map[string]*Container, len(previous))
+	for i := range previous {
+		previousByID[previous[i].ID] = &previous[i]
+	}
+
+	currentIDs := make(map[string]struct{}, len(current))
+	for i := range current {
+		currentIDs[current[i].ID] = struct{}{}
+		if p, ok := previousByID[current[i].ID]; !ok {
+			added = append(added, current[i])
+		} else if !p.Equal(current[i]) {
+			changed = append(changed, current[i])
+		}
+	}
+
+	for i := range previous {
+		if _, ok := currentIDs[previous[i].ID]; !ok {
+			removed = append(removed, previous[i])
+		}
+	}
+	return added, removed, changed
+}
+
+// Fingerprint returns a hash of the given containers, as used to determine
+// whether they have changed. The order of the containers doesn't matter. It can
+// be persisted and passed to WithSeedFingerprint to avoid a redundant initial
+// callback after restarting.
+//
+// Fingerprints are stable for a given version of this package, but may change
+// between versions (e.g. if fields are added to Container). A fingerprint from
+// a different version will just fail to match, causing one extra callback.
+// Fingerprints don't account for WithHashFields or WithIgnoredLabels.
+func Fingerprint(containers []Container) uint64 {
+	return computeHash(containers)
+}
+
+// GroupByLabel groups the given containers by the value of the label with the
+// given key. Containers without the label are grouped under missing (which may
+// be "", although that is also the key for containers with the label set to an
+// empty value). Containers appear in each group in the order they were given,
+// so sorted input (such as the containers passed to callbacks) produces sorted
+// groups.
+func GroupByLabel(containers []Container, key, missing string) map[string][]Container {
+	groups := make(map[string][]Container)
+	for i := range containers {
+		value, ok := containers[i].Labels[key]
+		if !ok {
+			value = missing
+		}
+		groups[value] = append(groups[value], containers[i])
+	}
+	return groups
+}
+
+// Canonical returns a deep copy of the given containers with all ordering
+// normalised: containers are sorted by ID, networks by name and ID, aliases
+// alphabetically, and ports by container port, protocol, host IP and host port.
+// Equivalent container lists produce identical output, making the result
+// suitable for stable serialisation (e.g. in golden files).
+func Canonical(containers []Container) []Container {
+	if containers == nil {
+		return nil
+	}
+
+	result := make([]Container, len(containers))
+	for i := range containers {
+		result[i] = containers[i].canonical()
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// canonical returns a deep copy of the Container with networks and ports sorted.
+func (c *Container) canonical() Container {
+	cc := *c
+
+	if c.Labels != nil {
+		cc.Labels = make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			cc.Labels[k] = v
+		}
+	}
+
+	if c.Networks != nil {
+		cc.Networks = make([]Network, len(c.Networks))
+		for i := range c.Networks {
+			cc.Networks[i] = c.Networks[i].canonical()
+		}
+		sort.Slice(cc.Networks, func(i, j int) bool {
+			a, b := cc.Networks[i], cc.Networks[j]
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.ID < b.ID
+		})
+	}
+
+	if c.Command != nil {
+		cc.Command = make([]string, len(c.Command))
+		copy(cc.Command, c.Command)
+	}
+
+	if c.Swarm != nil {
+		swarm := *c.Swarm
+		cc.Swarm = &swarm
+	}
+
+	if c.Ports != nil {
+		cc.Ports = make([]Port, len(c.Ports))
+		copy(cc.Ports, c.Ports)
+		sort.Slice(cc.Ports, func(i, j int) bool {
+			a, b := cc.Ports[i], cc.Ports[j]
+			if a.ContainerPort != b.ContainerPort {
+				return a.ContainerPort < b.ContainerPort
+			}
+			if a.Protocol != b.Protocol {
+				return a.Protocol < b.Protocol
+			}
+			if a.HostIP != b.HostIP {
+				return a.HostIP < b.HostIP
+			}
+			return a.HostPort < b.HostPort
+		})
+	}
+
+	return cc
+}
+
+// canonical returns a deep copy of the Network with aliases sorted.
+func (n *Network) canonical() Network {
+	cn := *n
+	if n.Aliases != nil {
+		cn.Aliases = make([]string, len(n.Aliases))
+		copy(cn.Aliases, n.Aliases)
+		sort.Strings(cn.Aliases)
+	}
+	if n.DriverOpts != nil {
+		cn.DriverOpts = make(map[string]string, len(n.DriverOpts))
+		for k, v := range n.DriverOpts {
+			cn.DriverOpts[k] = v
+		}
+	}
+	if n.Labels != nil {
+		cn.Labels = make(map[string]string, len(n.Labels))
+		for k, v := range n.Labels {
+			cn.Labels[k] = v
+		}
+	}
+	return cn
+}
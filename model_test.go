@@ -1,6 +1,14 @@
 package containuum
 
-import "testing"
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestPortHash(t *testing.T) {
 	t.Run("identical ports produce same hash", func(t *testing.T) {
@@ -113,6 +121,100 @@ func TestNetworkHash(t *testing.T) {
 	})
 }
 
+func TestNetworkHash_MacAndDriverOpts(t *testing.T) {
+	t.Run("different MAC address produces different hash", func(t *testing.T) {
+		n1 := Network{Name: "bridge", ID: "abc123", MacAddress: "02:42:ac:11:00:02"}
+		n2 := Network{Name: "bridge", ID: "abc123", MacAddress: "02:42:ac:11:00:03"}
+
+		if n1.hash() == n2.hash() {
+			t.Error("different MAC addresses should produce different hashes")
+		}
+
+		c1 := Container{ID: "container123", Networks: []Network{n1}}
+		c2 := Container{ID: "container123", Networks: []Network{n2}}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different network MAC addresses should produce different container hashes")
+		}
+	})
+
+	t.Run("driver opts in different map order produce same hash", func(t *testing.T) {
+		n1 := Network{Name: "bridge", DriverOpts: map[string]string{"a": "1", "b": "2"}}
+		n2 := Network{Name: "bridge", DriverOpts: map[string]string{"b": "2", "a": "1"}}
+
+		if n1.hash() != n2.hash() {
+			t.Error("driver opts in different order should produce the same hash")
+		}
+	})
+
+	t.Run("different driver opts produce different hash", func(t *testing.T) {
+		n1 := Network{Name: "bridge", DriverOpts: map[string]string{"a": "1"}}
+		n2 := Network{Name: "bridge", DriverOpts: map[string]string{"a": "2"}}
+
+		if n1.hash() == n2.hash() {
+			t.Error("different driver opts should produce different hashes")
+		}
+	})
+}
+
+func TestNetworkHash_Labels(t *testing.T) {
+	t.Run("different labels produce different hash", func(t *testing.T) {
+		n1 := Network{Name: "web", Labels: map[string]string{"tier": "frontend"}}
+		n2 := Network{Name: "web", Labels: map[string]string{"tier": "backend"}}
+
+		if n1.hash() == n2.hash() {
+			t.Error("different labels should produce different hashes")
+		}
+	})
+
+	t.Run("labels in different map order produce same hash", func(t *testing.T) {
+		n1 := Network{Name: "web", Labels: map[string]string{"a": "1", "b": "2"}}
+		n2 := Network{Name: "web", Labels: map[string]string{"b": "2", "a": "1"}}
+
+		if n1.hash() != n2.hash() {
+			t.Error("labels in different order should produce the same hash")
+		}
+	})
+
+	t.Run("labels are distinct from driver opts", func(t *testing.T) {
+		n1 := Network{Name: "web", DriverOpts: map[string]string{"a": "1"}}
+		n2 := Network{Name: "web", Labels: map[string]string{"a": "1"}}
+
+		if n1.hash() == n2.hash() {
+			t.Error("the same entries in labels and driver opts should produce different hashes")
+		}
+	})
+}
+
+func TestNetworkHash_PrefixLen(t *testing.T) {
+	t.Run("different prefix length produces different hash", func(t *testing.T) {
+		n1 := Network{Name: "bridge", IPAddress: "172.17.0.2", IPPrefixLen: 16}
+		n2 := Network{Name: "bridge", IPAddress: "172.17.0.2", IPPrefixLen: 24}
+
+		if n1.hash() == n2.hash() {
+			t.Error("different IPv4 prefix lengths should produce different hashes")
+		}
+	})
+
+	t.Run("different IPv6 prefix length produces different hash", func(t *testing.T) {
+		n1 := Network{Name: "bridge", IP6Address: "fd00::2", IP6PrefixLen: 64}
+		n2 := Network{Name: "bridge", IP6Address: "fd00::2", IP6PrefixLen: 48}
+
+		if n1.hash() == n2.hash() {
+			t.Error("different IPv6 prefix lengths should produce different hashes")
+		}
+	})
+
+	t.Run("network without IPv6 hashes stably", func(t *testing.T) {
+		n1 := Network{Name: "bridge", IPAddress: "172.17.0.2", IPPrefixLen: 16}
+		n2 := Network{Name: "bridge", IPAddress: "172.17.0.2", IPPrefixLen: 16, IP6PrefixLen: 0}
+
+		if n1.hash() != n2.hash() {
+			t.Error("networks without IPv6 should produce the same hash")
+		}
+	})
+}
+
 func TestContainerHash(t *testing.T) {
 	t.Run("identical containers produce same hash", func(t *testing.T) {
 		c1 := Container{
@@ -194,7 +296,7 @@ func TestContainerHash(t *testing.T) {
 		}
 
 		if c1.hash() != c2.hash() {
-			t.Error("networks in different order should produce the same hash (XOR is commutative)")
+			t.Error("networks in different order should produce the same hash")
 		}
 	})
 
@@ -215,7 +317,7 @@ func TestContainerHash(t *testing.T) {
 		}
 
 		if c1.hash() != c2.hash() {
-			t.Error("ports in different order should produce the same hash (XOR is commutative)")
+			t.Error("ports in different order should produce the same hash")
 		}
 	})
 
@@ -243,6 +345,180 @@ func TestContainerHash(t *testing.T) {
 		}
 	})
 
+	t.Run("different stop signal produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", StopSignal: "SIGTERM"}
+		c2 := Container{ID: "container123", StopSignal: "SIGQUIT"}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different stop signals should produce different hashes")
+		}
+	})
+
+	t.Run("different stop timeout produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", StopTimeout: 10}
+		c2 := Container{ID: "container123", StopTimeout: 30}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different stop timeouts should produce different hashes")
+		}
+	})
+
+	t.Run("different pid mode produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", PidMode: "host"}
+		c2 := Container{ID: "container123", PidMode: "container:abc"}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different pid modes should produce different hashes")
+		}
+	})
+
+	t.Run("different command produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", Command: []string{"sleep", "30"}}
+		c2 := Container{ID: "container123", Command: []string{"sleep", "60"}}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different commands should produce different hashes")
+		}
+	})
+
+	t.Run("command order is significant", func(t *testing.T) {
+		c1 := Container{ID: "container123", Command: []string{"-a", "-b"}}
+		c2 := Container{ID: "container123", Command: []string{"-b", "-a"}}
+
+		if c1.hash() == c2.hash() {
+			t.Error("commands with different argument order should produce different hashes")
+		}
+	})
+
+	t.Run("command argument boundaries are significant", func(t *testing.T) {
+		c1 := Container{ID: "container123", Command: []string{"ab"}}
+		c2 := Container{ID: "container123", Command: []string{"a", "b"}}
+
+		if c1.hash() == c2.hash() {
+			t.Error("commands with different argument boundaries should produce different hashes")
+		}
+	})
+
+	t.Run("different health produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", Health: "starting"}
+		c2 := Container{ID: "container123", Health: "healthy"}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different health statuses should produce different hashes")
+		}
+	})
+
+	t.Run("OOM killed produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", State: "exited"}
+		c2 := Container{ID: "container123", State: "exited", OOMKilled: true}
+
+		if c1.hash() == c2.hash() {
+			t.Error("OOM killed flag should affect the hash")
+		}
+	})
+
+	t.Run("swarm info produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123"}
+		c2 := Container{ID: "container123", Swarm: &SwarmInfo{}}
+		c3 := Container{ID: "container123", Swarm: &SwarmInfo{ServiceName: "web", TaskSlot: 1}}
+		c4 := Container{ID: "container123", Swarm: &SwarmInfo{ServiceName: "web", TaskSlot: 2}}
+
+		if c1.hash() == c2.hash() {
+			t.Error("swarm and non-swarm containers should produce different hashes")
+		}
+		if c3.hash() == c4.hash() {
+			t.Error("different task slots should produce different hashes")
+		}
+	})
+
+	t.Run("different created time produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+		c2 := Container{ID: "container123", Created: time.Date(2025, 1, 1, 0, 0, 0, 1, time.UTC)}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different created times should produce different hashes")
+		}
+		if c1.hashWith(hashOptions{fields: AllFields &^ FieldCreated}) != c2.hashWith(hashOptions{fields: AllFields &^ FieldCreated}) {
+			t.Error("created time should be ignored when FieldCreated is excluded")
+		}
+	})
+
+	t.Run("different log path produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", LogPath: "/var/log/a.log"}
+		c2 := Container{ID: "container123", LogPath: "/var/log/b.log"}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different log paths should produce different hashes")
+		}
+		if c1.hashWith(hashOptions{fields: AllFields &^ FieldLogPath}) != c2.hashWith(hashOptions{fields: AllFields &^ FieldLogPath}) {
+			t.Error("log path should be ignored when FieldLogPath is excluded")
+		}
+	})
+
+	t.Run("different pid produces same hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", Pid: 100}
+		c2 := Container{ID: "container123", Pid: 200}
+
+		if c1.hash() != c2.hash() {
+			t.Error("pid should not affect the hash")
+		}
+	})
+
+	t.Run("different image ID with same tag produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", Image: "nginx:latest", ImageID: "sha256:aaa"}
+		c2 := Container{ID: "container123", Image: "nginx:latest", ImageID: "sha256:bbb"}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different image IDs should produce different hashes")
+		}
+	})
+
+	t.Run("internal network produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", Networks: []Network{{Name: "backend", IPAddress: "172.20.0.2"}}}
+		c2 := Container{ID: "container123", Networks: []Network{{Name: "backend", IPAddress: "172.20.0.2", Internal: true}}}
+
+		if c1.hash() == c2.hash() {
+			t.Error("internal flag should affect the hash")
+		}
+	})
+
+	t.Run("duplicate ports don't cancel out", func(t *testing.T) {
+		port := Port{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}
+		none := Container{ID: "container123"}
+		single := Container{ID: "container123", Ports: []Port{port}}
+		duplicate := Container{ID: "container123", Ports: []Port{port, port}}
+
+		if duplicate.hash() == none.hash() {
+			t.Error("duplicate ports should not produce the same hash as no ports")
+		}
+		if duplicate.hash() == single.hash() {
+			t.Error("duplicate ports should not produce the same hash as the deduplicated ports")
+		}
+	})
+
+	t.Run("duplicate networks don't cancel out", func(t *testing.T) {
+		network := Network{Name: "bridge", ID: "net1", IPAddress: "172.17.0.2"}
+		none := Container{ID: "container123"}
+		single := Container{ID: "container123", Networks: []Network{network}}
+		duplicate := Container{ID: "container123", Networks: []Network{network, network}}
+
+		if duplicate.hash() == none.hash() {
+			t.Error("duplicate networks should not produce the same hash as no networks")
+		}
+		if duplicate.hash() == single.hash() {
+			t.Error("duplicate networks should not produce the same hash as the deduplicated networks")
+		}
+	})
+
+	t.Run("different restart policy produces different hash", func(t *testing.T) {
+		c1 := Container{ID: "container123", RestartPolicy: "always"}
+		c2 := Container{ID: "container123", RestartPolicy: "no"}
+
+		if c1.hash() == c2.hash() {
+			t.Error("different restart policies should produce different hashes")
+		}
+	})
+
 	t.Run("empty container", func(t *testing.T) {
 		c := Container{}
 		h := c.hash()
@@ -281,6 +557,42 @@ func TestContainerHash(t *testing.T) {
 	})
 }
 
+func TestContainerHashFields(t *testing.T) {
+	c1 := Container{ID: "container123", State: "running", Labels: map[string]string{"heartbeat": "1"}}
+	c2 := Container{ID: "container123", State: "running", Labels: map[string]string{"heartbeat": "2"}}
+	c3 := Container{ID: "container123", State: "exited", Labels: map[string]string{"heartbeat": "1"}}
+
+	opts := hashOptions{fields: FieldID | FieldState}
+
+	if c1.hashWith(opts) != c2.hashWith(opts) {
+		t.Error("changes to excluded fields should not change the hash")
+	}
+
+	if c1.hashWith(opts) == c3.hashWith(opts) {
+		t.Error("changes to included fields should change the hash")
+	}
+
+	if c1.hashWith(hashOptions{fields: AllFields}) != c1.hash() {
+		t.Error("hashing all fields should match the default hash")
+	}
+}
+
+func TestContainerHashIgnoredLabels(t *testing.T) {
+	c1 := Container{ID: "container123", Labels: map[string]string{"app": "web", "config-hash": "abc"}}
+	c2 := Container{ID: "container123", Labels: map[string]string{"app": "web", "config-hash": "def"}}
+	c3 := Container{ID: "container123", Labels: map[string]string{"app": "api", "config-hash": "abc"}}
+
+	opts := hashOptions{fields: AllFields, ignoredLabels: map[string]struct{}{"config-hash": {}}}
+
+	if c1.hashWith(opts) != c2.hashWith(opts) {
+		t.Error("changes to ignored labels should not change the hash")
+	}
+
+	if c1.hashWith(opts) == c3.hashWith(opts) {
+		t.Error("changes to other labels should change the hash")
+	}
+}
+
 func TestContainerListHash(t *testing.T) {
 	t.Run("identical container lists produce same hash", func(t *testing.T) {
 		containers1 := []Container{
@@ -343,3 +655,423 @@ func TestContainerListHash(t *testing.T) {
 		}
 	})
 }
+
+func TestCanonical(t *testing.T) {
+	t.Run("shuffled inputs produce identical output", func(t *testing.T) {
+		containers1 := []Container{
+			{
+				ID:     "c2",
+				Labels: map[string]string{"a": "1", "b": "2"},
+				Networks: []Network{
+					{Name: "custom", ID: "net2", Aliases: []string{"web", "api"}},
+					{Name: "bridge", ID: "net1"},
+				},
+				Ports: []Port{
+					{HostPort: 8443, ContainerPort: 443, Protocol: "tcp"},
+					{HostPort: 8080, ContainerPort: 80, Protocol: "udp"},
+					{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+				},
+			},
+			{ID: "c1", State: "running"},
+		}
+		containers2 := []Container{
+			{ID: "c1", State: "running"},
+			{
+				ID:     "c2",
+				Labels: map[string]string{"b": "2", "a": "1"},
+				Networks: []Network{
+					{Name: "bridge", ID: "net1"},
+					{Name: "custom", ID: "net2", Aliases: []string{"api", "web"}},
+				},
+				Ports: []Port{
+					{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+					{HostPort: 8443, ContainerPort: 443, Protocol: "tcp"},
+					{HostPort: 8080, ContainerPort: 80, Protocol: "udp"},
+				},
+			},
+		}
+
+		assert.Equal(t, Canonical(containers1), Canonical(containers2))
+	})
+
+	t.Run("does not modify input", func(t *testing.T) {
+		containers := []Container{
+			{ID: "c2", Networks: []Network{{Name: "b", Aliases: []string{"z", "y"}}, {Name: "a"}}},
+			{ID: "c1"},
+		}
+
+		result := Canonical(containers)
+
+		assert.Equal(t, "c1", result[0].ID)
+		assert.Equal(t, "a", result[1].Networks[0].Name)
+		assert.Equal(t, []string{"y", "z"}, result[1].Networks[1].Aliases)
+
+		assert.Equal(t, "c2", containers[0].ID)
+		assert.Equal(t, "b", containers[0].Networks[0].Name)
+		assert.Equal(t, []string{"z", "y"}, containers[0].Networks[0].Aliases)
+	})
+
+	t.Run("does not share command with input", func(t *testing.T) {
+		containers := []Container{{ID: "c1", Command: []string{"nginx", "-g", "daemon off;"}}}
+
+		result := Canonical(containers)
+		result[0].Command[0] = "httpd"
+
+		assert.Equal(t, []string{"nginx", "-g", "daemon off;"}, containers[0].Command)
+		assert.Equal(t, []string{"httpd", "-g", "daemon off;"}, result[0].Command)
+	})
+
+	t.Run("nil input", func(t *testing.T) {
+		assert.Nil(t, Canonical(nil))
+	})
+}
+
+func TestDiff(t *testing.T) {
+	previous := []Container{
+		{ID: "kept", State: "running"},
+		{ID: "changed", State: "running"},
+		{ID: "removed", State: "running"},
+	}
+	current := []Container{
+		{ID: "kept", State: "running"},
+		{ID: "changed", State: "exited"},
+		{ID: "added", State: "running"},
+	}
+
+	added, removed, changed := Diff(previous, current)
+
+	assert.Equal(t, []Container{{ID: "added", State: "running"}}, added)
+	assert.Equal(t, []Container{{ID: "removed", State: "running"}}, removed)
+	assert.Equal(t, []Container{{ID: "changed", State: "exited"}}, changed)
+}
+
+func TestFingerprint(t *testing.T) {
+	containers := []Container{
+		{ID: "c1", State: "running", Labels: map[string]string{"app": "web"}},
+		{ID: "c2", State: "exited", Ports: []Port{{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}},
+	}
+
+	assert.Equal(t, computeHash(containers), Fingerprint(containers))
+	assert.Equal(t, Fingerprint(containers), Fingerprint([]Container{containers[1], containers[0]}))
+	assert.NotEqual(t, Fingerprint(containers), Fingerprint(containers[:1]))
+}
+
+func TestGroupByLabel(t *testing.T) {
+	const project = "com.docker.compose.project"
+	containers := []Container{
+		{ID: "1", Labels: map[string]string{project: "web"}},
+		{ID: "2", Labels: map[string]string{project: "db"}},
+		{ID: "3"},
+		{ID: "4", Labels: map[string]string{project: "web"}},
+		{ID: "5", Labels: map[string]string{"other": "web"}},
+	}
+
+	t.Run("groups by label value", func(t *testing.T) {
+		groups := GroupByLabel(containers, project, "(none)")
+		assert.Equal(t, map[string][]Container{
+			"web":    {containers[0], containers[3]},
+			"db":     {containers[1]},
+			"(none)": {containers[2], containers[4]},
+		}, groups)
+	})
+
+	t.Run("preserves order within groups", func(t *testing.T) {
+		reversed := slices.Clone(containers)
+		slices.Reverse(reversed)
+
+		groups := GroupByLabel(reversed, project, "")
+		assert.Equal(t, []Container{containers[3], containers[0]}, groups["web"])
+		assert.Equal(t, []Container{containers[4], containers[2]}, groups[""])
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		assert.Empty(t, GroupByLabel(nil, project, ""))
+	})
+}
+
+func TestServicePort(t *testing.T) {
+	servicePort := ServicePort("port")
+
+	tests := []struct {
+		name      string
+		container Container
+		wantPort  uint16
+		wantOK    bool
+	}{
+		{
+			name: "label specified",
+			container: Container{
+				Labels: map[string]string{"port": "8080"},
+				Ports: []Port{
+					{HostPort: 80, ContainerPort: 80, Protocol: "tcp"},
+					{HostPort: 443, ContainerPort: 443, Protocol: "tcp"},
+				},
+			},
+			wantPort: 8080,
+			wantOK:   true,
+		},
+		{
+			name:      "invalid label",
+			container: Container{Labels: map[string]string{"port": "http"}},
+			wantOK:    false,
+		},
+		{
+			name: "single published port",
+			container: Container{
+				Ports: []Port{{HostIP: "0.0.0.0", HostPort: 32768, ContainerPort: 3000, Protocol: "tcp"}},
+			},
+			wantPort: 3000,
+			wantOK:   true,
+		},
+		{
+			name: "single port published on multiple addresses",
+			container: Container{
+				Ports: []Port{
+					{HostIP: "0.0.0.0", HostPort: 32768, ContainerPort: 3000, Protocol: "tcp"},
+					{HostIP: "::", HostPort: 32768, ContainerPort: 3000, Protocol: "tcp"},
+				},
+			},
+			wantPort: 3000,
+			wantOK:   true,
+		},
+		{
+			name: "multiple published ports",
+			container: Container{
+				Ports: []Port{
+					{HostPort: 80, ContainerPort: 80, Protocol: "tcp"},
+					{HostPort: 443, ContainerPort: 443, Protocol: "tcp"},
+				},
+			},
+			wantOK: false,
+		},
+		{
+			name:      "no ports",
+			container: Container{},
+			wantOK:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, ok := servicePort(tt.container)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantPort, port)
+		})
+	}
+}
+
+func TestContainerJSON(t *testing.T) {
+	c := Container{
+		ID:      "abc123",
+		Name:    "web",
+		Image:   "nginx:latest",
+		State:   "running",
+		Created: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels: map[string]string{
+			"app": "web",
+		},
+		Networks: []Network{
+			{
+				Name:        "bridge",
+				ID:          "net1",
+				IPAddress:   "172.17.0.2",
+				IPPrefixLen: 16,
+				Gateway:     "172.17.0.1",
+				Aliases:     []string{"web"},
+				MacAddress:  "02:42:ac:11:00:02",
+			},
+		},
+		Ports: []Port{
+			{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+		StopSignal:    "SIGQUIT",
+		StopTimeout:   10,
+		RestartPolicy: "always",
+		Command:       []string{"nginx", "-g", "daemon off;"},
+		LogPath:       "/var/lib/docker/containers/abc123/abc123-json.log",
+		Pid:           4242,
+		SizeRw:        1024,
+		SizeRootFs:    4096,
+	}
+
+	data, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "abc123",
+		"name": "web",
+		"image": "nginx:latest",
+		"imageId": "",
+		"state": "running",
+		"created": "2025-01-02T03:04:05Z",
+		"labels": {"app": "web"},
+		"networks": [{
+			"name": "bridge",
+			"id": "net1",
+			"ipAddress": "172.17.0.2",
+			"ip6Address": "",
+			"ipPrefixLen": 16,
+			"ip6PrefixLen": 0,
+			"gateway": "172.17.0.1",
+			"aliases": ["web"],
+			"macAddress": "02:42:ac:11:00:02",
+			"internal": false
+		}],
+		"ports": [{"hostIp": "0.0.0.0", "hostPort": 8080, "containerPort": 80, "protocol": "tcp"}],
+		"stopSignal": "SIGQUIT",
+		"stopTimeout": 10,
+		"pidMode": "",
+		"ipcMode": "",
+		"restartPolicy": "always",
+		"health": "",
+		"oomKilled": false,
+		"logPath": "/var/lib/docker/containers/abc123/abc123-json.log",
+		"pid": 4242,
+		"sizeRw": 1024,
+		"sizeRootFs": 4096,
+		"command": ["nginx", "-g", "daemon off;"]
+	}`, string(data))
+
+	var decoded Container
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, c, decoded)
+}
+
+func TestContainerJSON_OmitsEmptyCollections(t *testing.T) {
+	data, err := json.Marshal(Container{ID: "abc123"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "abc123",
+		"name": "",
+		"image": "",
+		"imageId": "",
+		"state": "",
+		"stopSignal": "",
+		"stopTimeout": 0,
+		"pidMode": "",
+		"ipcMode": "",
+		"restartPolicy": "",
+		"health": "",
+		"oomKilled": false
+	}`, string(data))
+}
+
+func TestContainerString(t *testing.T) {
+	t.Run("without ports", func(t *testing.T) {
+		c := Container{ID: "abc123", Name: "web", Image: "nginx:latest", State: "running"}
+		assert.Equal(t, "web (nginx:latest) [running]", c.String())
+		assert.Equal(t, "web (nginx:latest) [running]", c.Summary())
+	})
+
+	t.Run("with ports", func(t *testing.T) {
+		c := Container{
+			ID:    "abc123",
+			Name:  "web",
+			Image: "nginx:latest",
+			State: "running",
+			Ports: []Port{
+				{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+				{HostIP: "0.0.0.0", HostPort: 5353, ContainerPort: 53, Protocol: "udp"},
+			},
+		}
+		assert.Equal(t, "web (nginx:latest) [running] 8080->80/tcp, 5353->53/udp", c.String())
+		assert.Equal(t, "web (nginx:latest) [running]", c.Summary())
+	})
+
+	t.Run("without name", func(t *testing.T) {
+		c := Container{ID: "abc123", Image: "nginx:latest", State: "exited"}
+		assert.Equal(t, "abc123 (nginx:latest) [exited]", c.String())
+	})
+}
+
+func TestContainerLabelsWithPrefix(t *testing.T) {
+	c := Container{
+		Labels: map[string]string{
+			"traefik.enable":                  "true",
+			"traefik.http.routers.web.rule":   "Host(`example.com`)",
+			"com.docker.compose.project":      "myapp",
+			"traefik":                         "bare",
+			"org.opencontainers.image.source": "https://example.com",
+		},
+	}
+
+	assert.Equal(t, map[string]string{
+		"traefik.enable":                "true",
+		"traefik.http.routers.web.rule": "Host(`example.com`)",
+	}, c.LabelsWithPrefix("traefik."))
+
+	assert.Nil(t, c.LabelsWithPrefix("com.example."))
+	assert.Nil(t, Container{}.LabelsWithPrefix("traefik."))
+}
+
+func TestContainerCompose(t *testing.T) {
+	c := Container{
+		Labels: map[string]string{
+			"com.docker.compose.project": "myapp",
+			"com.docker.compose.service": "web",
+		},
+	}
+	assert.Equal(t, "myapp", c.ComposeProject())
+	assert.Equal(t, "web", c.ComposeService())
+
+	c = Container{Labels: map[string]string{"app": "web"}}
+	assert.Empty(t, c.ComposeProject())
+	assert.Empty(t, c.ComposeService())
+
+	assert.Empty(t, Container{}.ComposeProject())
+	assert.Empty(t, Container{}.ComposeService())
+}
+
+func TestContainerEqual(t *testing.T) {
+	c := Container{
+		ID:       "container123",
+		Name:     "web",
+		Networks: []Network{{Name: "a", ID: "1"}, {Name: "b", ID: "2"}},
+		Ports:    []Port{{HostPort: 80, ContainerPort: 80}, {HostPort: 443, ContainerPort: 443}},
+	}
+
+	t.Run("identical containers are equal", func(t *testing.T) {
+		assert.True(t, c.Equal(c.canonical()))
+	})
+
+	t.Run("order of networks and ports is not significant", func(t *testing.T) {
+		other := c.canonical()
+		other.Networks[0], other.Networks[1] = other.Networks[1], other.Networks[0]
+		other.Ports[0], other.Ports[1] = other.Ports[1], other.Ports[0]
+		assert.True(t, c.Equal(other))
+	})
+
+	t.Run("renamed container is not equal", func(t *testing.T) {
+		other := c.canonical()
+		other.Name = "web-renamed"
+		assert.False(t, c.Equal(other))
+	})
+}
+
+func TestDiff_OrderIndependentAndRenames(t *testing.T) {
+	previous := []Container{
+		{ID: "a", Name: "web", State: "running"},
+		{ID: "b", Name: "api", State: "running"},
+		{ID: "c", Name: "db", State: "running"},
+	}
+	current := []Container{
+		{ID: "d", Name: "cache", State: "running"},
+		{ID: "b", Name: "api-v2", State: "running"},
+		{ID: "a", Name: "web", State: "running"},
+	}
+
+	added, removed, changed := Diff(previous, current)
+
+	assert.Equal(t, []Container{{ID: "d", Name: "cache", State: "running"}}, added)
+	assert.Equal(t, []Container{{ID: "c", Name: "db", State: "running"}}, removed)
+	assert.Equal(t, []Container{{ID: "b", Name: "api-v2", State: "running"}}, changed, "rename should be an update, not a remove and add")
+}
+
+func TestStateConstants(t *testing.T) {
+	assert.Equal(t, string(container.StateCreated), StateCreated)
+	assert.Equal(t, string(container.StateRunning), StateRunning)
+	assert.Equal(t, string(container.StatePaused), StatePaused)
+	assert.Equal(t, string(container.StateRestarting), StateRestarting)
+	assert.Equal(t, string(container.StateRemoving), StateRemoving)
+	assert.Equal(t, string(container.StateExited), StateExited)
+	assert.Equal(t, string(container.StateDead), StateDead)
+}
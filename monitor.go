@@ -2,101 +2,324 @@ package containuum
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 )
 
-// eventFilters are the Docker events we subscribe to.
-var eventFilters = filters.NewArgs(
-	filters.Arg("type", "container"),
-	filters.Arg("type", "network"),
-	filters.Arg("event", "create"),
-	filters.Arg("event", "start"),
-	filters.Arg("event", "stop"),
-	filters.Arg("event", "die"),
-	filters.Arg("event", "kill"),
-	filters.Arg("event", "pause"),
-	filters.Arg("event", "unpause"),
-	filters.Arg("event", "rename"),
-	filters.Arg("event", "update"),
-	filters.Arg("event", "destroy"),
-	filters.Arg("event", "connect"),
-	filters.Arg("event", "disconnect"),
+// defaultActions are the actions of the Docker events we subscribe to by default.
+var defaultActions = []string{
+	"create",
+	"start",
+	"restart",
+	"stop",
+	"die",
+	"kill",
+	"pause",
+	"unpause",
+	"rename",
+	"update",
+	"destroy",
+	"health_status",
+	"oom",
+	"connect",
+	"disconnect",
+}
+
+// eventFilters returns the filters used to subscribe to container and network
+// events with the given actions. If any containers are given, only events for
+// those containers are subscribed to.
+func eventFilters(actions, containers []string) filters.Args {
+	args := filters.NewArgs(
+		filters.Arg("type", "container"),
+		filters.Arg("type", "network"),
+	)
+	for _, action := range actions {
+		args.Add("event", action)
+	}
+	for _, id := range containers {
+		args.Add("container", id)
+	}
+	return args
+}
+
+// inspectRetryDelay is the delay before the first retry of a failed inspect.
+// Subsequent retries wait proportionally longer.
+const inspectRetryDelay = 100 * time.Millisecond
+
+// Startup states, used to enforce the startup timeout.
+const (
+	startupPending int32 = iota
+	startupComplete
+	startupTimedOut
 )
 
+// containerSize holds the sizes of a container's filesystem, as reported when
+// listing containers.
+type containerSize struct {
+	rw     int64
+	rootFs int64
+}
+
+// unsettledContainer records when events for a container started and most
+// recently arrived, for per-container debouncing.
+type unsettledContainer struct {
+	first time.Time
+	last  time.Time
+}
+
+// clientRecreateAttempts is the number of consecutive failed reconnection
+// attempts after which the Docker client is recreated, if possible. The
+// connection used by the client may be permanently broken, e.g. if the daemon's
+// socket has been replaced.
+const clientRecreateAttempts = 3
+
+// errStreamClosed is returned by runOnce when the event stream ends without an error.
+var errStreamClosed = errors.New("event stream closed")
+
+// errStopped is returned by runOnce when the monitor is stopped with Monitor.Stop.
+var errStopped = errors.New("monitor stopped")
+
+// errListFailed is wrapped by errors returned when listing containers fails.
+var errListFailed = errors.New("failed to list containers")
+
 // reconnectConfig holds parameters for automatic reconnection.
 type reconnectConfig struct {
 	MinDelay   time.Duration
 	MaxDelay   time.Duration
 	MaxRetries int
+	Jitter     float64
+	StableTime time.Duration
+	ResetAfter time.Duration
+}
+
+// jittered returns the delay with a random portion, up to Jitter of the total, removed.
+func (r *reconnectConfig) jittered(delay time.Duration, random func() float64) time.Duration {
+	if r.Jitter <= 0 {
+		return delay
+	}
+	return delay - time.Duration(float64(delay)*r.Jitter*random())
 }
 
 // monitor consolidates all container monitoring logic.
 type monitor struct {
-	ctx      context.Context
-	client   DockerClient
-	callback Callback
-	filter   Filter
+	ctx            context.Context
+	client         DockerClient
+	filter         *atomic.Pointer[Filter]
+	asyncFilter    func(context.Context, Container) (bool, error)
+	watchIDs       []string
+	listFilters    filters.Args
+	transform      func(Container) Container
+	less           func(a, b Container) bool
+	trackStates    []string
+	hashOptions    hashOptions
+	dedupDisabled  bool
+	incremental    bool
+	inspectRetries int
+	maxContainers  int
+	limitPolicy    ContainerLimitPolicy
+	inspectCache   bool
+	pauseOnError   bool
+	summaryOnly    bool
+	sizes          bool
+	staleOnList    time.Duration
+	eventFilters   filters.Args
+	eventBuffer    int
+	refresh        <-chan chan error
+	stop           <-chan struct{}
+
+	// Used to populate network labels (nil = disabled)
+	networkInspector NetworkInspector
+
+	// Logging config
+	name             string
+	eventLogSampling int
+
+	// Callbacks
+	callback               Callback
+	contextCallback        ContextCallback
+	removedCallback        Callback
+	syntheticEventCallback func([]events.Message)
+	eventCallback          func(actions []string, containers []Container)
+	asyncCallback          bool
+	recoverPanics          bool
+
+	// Used to trace gathers and callbacks (nil = disabled)
+	tracer Tracer
 
 	// Timing config
-	debounce        time.Duration
-	maxDebounceTime time.Duration
-	maxIdleTime     time.Duration
+	connectTimeout      time.Duration
+	startupTimeout      time.Duration
+	debounce            time.Duration
+	debounceStrategy    DebounceStrategy
+	containerDebounce   time.Duration
+	maxDebounceTime     time.Duration
+	maxIdleTime         time.Duration
+	fullResyncInterval  time.Duration
+	minCallbackInterval time.Duration
+	callbackTimeout     time.Duration
+	eventGapThreshold   time.Duration
+	healthPollInterval  time.Duration
+
+	// Used to replace the Docker client after the given number of consecutive
+	// failures to reconnect (nil = the client was provided by the user, and
+	// can't be replaced)
+	newClient           func(context.Context) (DockerClient, func() error, error)
+	recreateClientAfter int
+	closeClient         func() error
+	checkClient         func(DockerClient) error
 
 	// Reconnect config (nil = disabled)
-	reconnect *reconnectConfig
+	reconnect               *reconnectConfig
+	random                  func() float64
+	connectionStateCallback func(connected bool, err error)
+
+	// State. previousHash is nil until the callback is first invoked, so that
+	// the initial state (even if empty) is always emitted. It is kept across
+	// reconnections, so only real changes are emitted after reconnecting.
+	previousHash       *uint64
+	healthStarting     bool
+	previousContainers []Container
+	connected          bool
+	lastListed         time.Time
+	eventCount         uint64
+	lastEvent          *atomic.Int64
+	lastCallbackTime   *atomic.Int64
+	currentCount       *atomic.Int64
+	startup            atomic.Int32
+	droppedEvents      atomic.Uint64
+	mailbox            chan []Container
+	actions            []string
+
+	// Rate limiting state
+	lastCallback time.Time
+	pending      []Container
+	hasPending   bool
+	rateTimer    *time.Timer
+
+	// Incremental update state
+	known      map[string]Container
+	dirty      map[string]struct{}
+	fullResync bool
+
+	// Per-container debounce state: when each unsettled container's events
+	// started and most recently arrived, and the containers last published
+	unsettled map[string]unsettledContainer
+	settled   map[string]Container
+
+	// Sizes of containers from when they were last listed, if sizes are enabled
+	listedSizes map[string]containerSize
+
+	// Inspect cache state
+	cached map[string]Container
 
-	// State
-	previousHash *uint64
+	// Network labels retrieved during the current refresh, by network ID
+	networkLabels map[string]map[string]string
 }
 
 // run starts monitoring and blocks until context is cancelled or an error occurs.
+//
+// If the event stream is closed cleanly (without an error) and auto-reconnect is
+// disabled, run returns nil. With auto-reconnect enabled, both clean closes and
+// errors cause a reconnection attempt.
 func (m *monitor) run() error {
+	if m.startupTimeout > 0 {
+		ctx, cancel := context.WithCancelCause(m.ctx)
+		defer cancel(nil)
+		m.ctx = ctx
+
+		timer := time.AfterFunc(m.startupTimeout, func() {
+			if m.startup.CompareAndSwap(startupPending, startupTimedOut) {
+				m.log("Startup timeout exceeded", "startupTimeout", m.startupTimeout)
+				cancel(ErrStartupTimeout)
+			}
+		})
+		defer timer.Stop()
+	}
+
+	if m.asyncCallback {
+		m.mailbox = make(chan []Container, 1)
+		done := make(chan struct{})
+		go m.deliverAsync(done)
+		defer func() {
+			close(m.mailbox)
+			<-done
+		}()
+	}
+
+	var err error
 	if m.reconnect != nil {
-		return m.runWithRetry()
+		err = m.runWithRetry()
+	} else if err = m.runOnce(0); errors.Is(err, errStreamClosed) {
+		// Without reconnection, a cleanly closed stream is a normal shutdown.
+		return nil
 	}
-	return m.runOnce()
+	if errors.Is(err, errStopped) {
+		return nil
+	}
+	if m.startup.Load() == startupTimedOut {
+		return fmt.Errorf("%w after %s: %w", ErrStartupTimeout, m.startupTimeout, err)
+	}
+	return err
 }
 
 // runWithRetry wraps runOnce with exponential backoff retry logic.
 func (m *monitor) runWithRetry() error {
 	attempt := 0
 	delay := m.reconnect.MinDelay
+	var stableTime time.Duration
 
 	for {
 		startTime := time.Now()
-		err := m.runOnce()
+		err := m.runOnce(stableTime)
+		stableTime = m.reconnect.StableTime
 
 		if m.ctx.Err() != nil {
 			return m.ctx.Err()
 		}
+		if errors.Is(err, errStopped) {
+			return err
+		}
 
-		if time.Since(startTime) >= time.Minute {
+		if time.Since(startTime) >= m.reconnect.ResetAfter {
 			attempt = 0
 			delay = m.reconnect.MinDelay
 		}
 
 		attempt++
 		if m.reconnect.MaxRetries > 0 && attempt > m.reconnect.MaxRetries {
-			Log("Max reconnect retries exceeded", "attempts", attempt)
-			return err
+			m.log("Max reconnect retries exceeded", "attempts", attempt)
+			return fmt.Errorf("%w (%d): %w", ErrMaxRetriesExceeded, m.reconnect.MaxRetries, err)
 		}
 
-		Log("Event stream disconnected, will reconnect", "attempt", attempt, "delay", delay, "error", err)
+		wait := m.reconnect.jittered(delay, m.random)
+		m.log("Event stream disconnected, will reconnect", "attempt", attempt, "delay", wait, "error", err)
 
 		select {
 		case <-m.ctx.Done():
 			return m.ctx.Err()
-		case <-time.After(delay):
+		case <-m.stop:
+			return errStopped
+		case <-time.After(wait):
+		}
+
+		if m.newClient != nil && attempt%m.recreateClientAfter == 0 {
+			m.recreateClient()
 		}
 
-		Log("Reconnecting to docker event stream")
+		m.log("Reconnecting to docker event stream")
 
 		delay *= 2
 		if delay > m.reconnect.MaxDelay {
@@ -105,19 +328,71 @@ func (m *monitor) runWithRetry() error {
 	}
 }
 
-// runOnce is the main event loop.
-func (m *monitor) runOnce() error {
+// recreateClient replaces the Docker client with a new one, closing the old
+// one. If the new client can't be created, the old one is kept.
+func (m *monitor) recreateClient() {
+	m.log("Recreating docker client")
+	newClient, closeClient, err := m.newClient(m.ctx)
+	if err == nil && m.checkClient != nil {
+		if err = m.checkClient(newClient); err != nil {
+			_ = closeClient()
+		}
+	}
+	if err != nil {
+		m.log("Failed to recreate docker client, keeping existing one", "error", err)
+		return
+	}
+
+	if m.closeClient != nil {
+		if err := m.closeClient(); err != nil {
+			m.log("Failed to close docker client", "error", err)
+		}
+	}
+	m.client, m.closeClient = newClient, closeClient
+	if m.networkInspector != nil {
+		m.networkInspector = newClient.(NetworkInspector)
+	}
+}
+
+// runOnce is the main event loop. If stableTime is non-zero, the event stream
+// must remain open for that long before the initial gather is performed.
+func (m *monitor) runOnce(stableTime time.Duration) (err error) {
+	defer func() {
+		if m.ctx.Err() == nil && !errors.Is(err, errStopped) {
+			m.setConnected(false, err)
+		}
+	}()
+
 	eventCh, errCh := m.client.Events(m.ctx, events.ListOptions{
-		Filters: eventFilters,
+		Filters: m.eventFilters,
 	})
+	if m.eventBuffer > 0 {
+		ctx, cancel := context.WithCancel(m.ctx)
+		defer cancel()
+		eventCh, errCh = m.bufferEvents(ctx, eventCh, errCh)
+	}
 
-	Log("Subscribed to docker events")
+	m.log("Subscribed to docker events")
+
+	if stableTime > 0 {
+		if err := m.waitStable(stableTime, eventCh, errCh); err != nil {
+			return err
+		}
+	}
+
+	// The rate timer must exist before the initial gather, as publishing may
+	// need to delay the callback until the minimum interval has elapsed.
+	m.rateTimer = time.NewTimer(0)
+	m.rateTimer.Stop()
+	defer m.rateTimer.Stop()
 
 	// Emit initial state immediately
-	if err := m.gather(); err != nil {
+	if err := m.initialGather(); err != nil {
 		return err
 	}
 
+	m.setConnected(true, nil)
+
 	debounceTimer := time.NewTimer(m.debounce)
 	debounceTimer.Stop()
 	defer debounceTimer.Stop()
@@ -129,133 +404,1107 @@ func (m *monitor) runOnce() error {
 	idleTicker := time.NewTicker(m.maxIdleTime)
 	defer idleTicker.Stop()
 
+	settleTimer := time.NewTimer(0)
+	settleTimer.Stop()
+	defer settleTimer.Stop()
+	if next, ok := m.nextSettle(); ok {
+		settleTimer.Reset(next)
+	}
+
+	var resyncCh <-chan time.Time
+	if m.fullResyncInterval > 0 {
+		resyncTicker := time.NewTicker(m.fullResyncInterval)
+		defer resyncTicker.Stop()
+		resyncCh = resyncTicker.C
+	}
+
+	var healthCh <-chan time.Time
+	if m.healthPollInterval > 0 {
+		healthTicker := time.NewTicker(m.healthPollInterval)
+		defer healthTicker.Stop()
+		healthCh = healthTicker.C
+	}
+
+	// Whether we're within a burst of events, and whether there are events in it
+	// that haven't yet resulted in a refresh.
 	waiting := false
+	unhandled := false
 
 	for {
+		// Stopping takes priority over anything else that is ready, so that no
+		// further refreshes are started once the monitor has been asked to stop.
+		select {
+		case <-m.stop:
+			return errStopped
+		default:
+		}
+
 		select {
 		case <-m.ctx.Done():
 			return m.ctx.Err()
 
+		case <-m.stop:
+			return errStopped
+
 		case err := <-errCh:
 			if err != nil {
 				return fmt.Errorf("failed to stream events: %w", err)
 			}
-			return nil
+			return errStreamClosed
+
+		case event, ok := <-eventCh:
+			if !ok {
+				return errStreamClosed
+			}
 
-		case event := <-eventCh:
-			Log("Received event from docker", "type", event.Type, "actor", event.Actor.ID, "action", event.Action)
+			m.eventCount++
+			if m.lastEvent != nil {
+				m.lastEvent.Store(time.Now().UnixNano())
+			}
+			if m.eventLogSampling <= 1 || (m.eventCount-1)%uint64(m.eventLogSampling) == 0 {
+				m.log("Received event from docker", "type", event.Type, "actor", event.Actor.ID, "action", event.Action, "count", m.eventCount)
+			}
+			if event.Actor.ID == "" {
+				// Events should always identify the object they relate to, but some
+				// daemons omit it. Any such event must still result in a full refresh.
+				m.log("Event has no actor ID, will perform full refresh", "type", event.Type, "action", event.Action)
+			}
+			if m.eventCallback != nil && !slices.Contains(m.actions, string(event.Action)) {
+				m.actions = append(m.actions, string(event.Action))
+			}
+			if m.incremental {
+				m.markDirty(event)
+			}
+			m.invalidateCached(event)
+			if m.unsettle(event) {
+				next, _ := m.nextSettle()
+				settleTimer.Reset(next)
+			}
 			idleTicker.Reset(m.maxIdleTime)
 
-			if waiting {
-				debounceTimer.Reset(m.debounce)
+			if m.overflowed() || m.missedEvents(event) {
+				if err := m.pause(m.refreshAll(m.ctx)); err != nil {
+					return err
+				}
+				if waiting {
+					debounceTimer.Stop()
+					maxDebounceTimer.Stop()
+					waiting, unhandled = false, false
+				}
+				continue
+			}
+
+			if !waiting && m.debounceStrategy == DebounceLeading {
+				if err := m.pause(m.update()); err != nil {
+					return err
+				}
 			} else {
-				debounceTimer.Reset(m.debounce)
+				unhandled = true
+			}
+
+			if !waiting {
 				maxDebounceTimer.Reset(m.maxDebounceTime)
 				waiting = true
 			}
+			debounceTimer.Reset(m.debounce)
 
 		case <-debounceTimer.C:
-			if err := m.gather(); err != nil {
-				return err
+			if unhandled {
+				if err := m.pause(m.update()); err != nil {
+					return err
+				}
 			}
 			maxDebounceTimer.Stop()
 			idleTicker.Reset(m.maxIdleTime)
-			waiting = false
+			waiting, unhandled = false, false
 
-		case <-maxDebounceTimer.C:
-			Log("Maximum debounce time exceeded, refreshing", "maxDebounceTime", m.maxDebounceTime, "debounce", m.debounce)
-			if err := m.gather(); err != nil {
+		case <-settleTimer.C:
+			m.log("Container settled, refreshing")
+			if err := m.pause(m.update()); err != nil {
 				return err
 			}
+			if next, ok := m.nextSettle(); ok {
+				settleTimer.Reset(next)
+			}
+
+		case result := <-m.refresh:
+			m.log("Refresh requested")
+			err := m.refreshAll(m.ctx)
+			result <- err
+			if err := m.pause(err); err != nil {
+				return err
+			}
+			if waiting {
+				debounceTimer.Stop()
+				maxDebounceTimer.Stop()
+				waiting, unhandled = false, false
+			}
+			idleTicker.Reset(m.maxIdleTime)
+
+		case <-maxDebounceTimer.C:
+			if unhandled {
+				m.log("Maximum debounce time exceeded, refreshing", "maxDebounceTime", m.maxDebounceTime, "debounce", m.debounce)
+				if err := m.pause(m.update()); err != nil {
+					return err
+				}
+			}
 			debounceTimer.Stop()
 			idleTicker.Reset(m.maxIdleTime)
-			waiting = false
+			waiting, unhandled = false, false
+
+		case <-resyncCh:
+			m.log("Full resync interval reached, refreshing", "fullResyncInterval", m.fullResyncInterval)
+			if err := m.pause(m.refreshAll(m.ctx)); err != nil {
+				return err
+			}
+			if waiting {
+				debounceTimer.Stop()
+				maxDebounceTimer.Stop()
+				waiting, unhandled = false, false
+			}
+			idleTicker.Reset(m.maxIdleTime)
+
+		case <-healthCh:
+			if !m.healthStarting {
+				continue
+			}
+			m.log("Containers are starting, refreshing to check health", "healthPollInterval", m.healthPollInterval)
+			if err := m.pause(m.refreshAll(m.ctx)); err != nil {
+				return err
+			}
+			if waiting {
+				debounceTimer.Stop()
+				maxDebounceTimer.Stop()
+				waiting, unhandled = false, false
+			}
+			idleTicker.Reset(m.maxIdleTime)
+
+		case <-m.rateTimer.C:
+			if m.hasPending {
+				m.log("Minimum callback interval elapsed, invoking callback")
+				if err := m.publish(m.ctx, m.pending); err != nil {
+					return err
+				}
+			}
 
 		case <-idleTicker.C:
-			Log("Maximum idle time exceeded, refreshing", "maxIdleTime", m.maxIdleTime)
-			if err := m.gather(); err != nil {
+			m.log("Maximum idle time exceeded, refreshing", "maxIdleTime", m.maxIdleTime)
+			if err := m.pause(m.refreshAll(m.ctx)); err != nil {
 				return err
 			}
 			if waiting {
 				debounceTimer.Stop()
 				maxDebounceTimer.Stop()
-				waiting = false
+				waiting, unhandled = false, false
+			}
+		}
+	}
+}
+
+// pause returns the given error from refreshing the containers, unless pausing
+// on errors is enabled (or the error is from listing containers, and the last
+// successful list is recent enough), in which case the error is logged and nil
+// is returned so that the monitor keeps running with the last known state. The
+// refresh will be retried after the next event, or when the idle time is
+// exceeded.
+func (m *monitor) pause(err error) error {
+	if err == nil || m.ctx.Err() != nil {
+		return err
+	}
+	if m.pauseOnError {
+		m.log("Failed to refresh containers, keeping last known state", "error", err)
+		return nil
+	}
+	if m.staleOnList > 0 && errors.Is(err, errListFailed) && !m.lastListed.IsZero() && time.Since(m.lastListed) <= m.staleOnList {
+		m.log("Failed to list containers, keeping last known state", "error", err, "age", time.Since(m.lastListed))
+		return nil
+	}
+	return err
+}
+
+// bufferEvents reads events from the given channels until the context is
+// cancelled, passing them on via a channel buffered to the configured size.
+// Events that arrive while the buffer is full are dropped and counted.
+func (m *monitor) bufferEvents(ctx context.Context, eventCh <-chan events.Message, errCh <-chan error) (<-chan events.Message, <-chan error) {
+	buffered := make(chan events.Message, m.eventBuffer)
+	errs := make(chan error, 1)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-eventCh:
+				if !ok {
+					close(buffered)
+					return
+				}
+				select {
+				case buffered <- event:
+				default:
+					m.droppedEvents.Add(1)
+				}
+			case err, ok := <-errCh:
+				if !ok {
+					errCh = nil
+					continue
+				}
+				errs <- err
+				return
 			}
 		}
+	}()
+
+	return buffered, errs
+}
+
+// overflowed determines whether any events have been dropped because the event
+// buffer was full since it was last called.
+func (m *monitor) overflowed() bool {
+	dropped := m.droppedEvents.Swap(0)
+	if dropped == 0 {
+		return false
 	}
+	m.log("Event buffer overflowed, will perform full refresh", "dropped", dropped)
+	return true
+}
+
+// missedEvents determines whether events appear to have been missed, based on
+// how long after the given event occurred it was received. Docker delivers
+// events promptly, so a large delay suggests the stream stalled (e.g. due to a
+// proxy) and other events may have been dropped in the meantime.
+func (m *monitor) missedEvents(event events.Message) bool {
+	if m.eventGapThreshold <= 0 || event.TimeNano == 0 {
+		return false
+	}
+
+	delay := time.Since(time.Unix(0, event.TimeNano))
+	if delay <= m.eventGapThreshold {
+		return false
+	}
+
+	m.log("Event received late, events may have been missed; refreshing", "delay", delay, "threshold", m.eventGapThreshold)
+	return true
+}
+
+// log logs the given message using Log, identifying the monitor if it has been
+// given a name.
+func (m *monitor) log(msg string, keysAndValues ...any) {
+	if m.name != "" {
+		keysAndValues = append([]any{"monitor", m.name}, keysAndValues...)
+	}
+	Log(msg, keysAndValues...)
+}
+
+// setConnected records the connection state, invoking the connection state
+// callback if it has changed.
+func (m *monitor) setConnected(connected bool, err error) {
+	if m.connected == connected {
+		return
+	}
+
+	m.connected = connected
+	if m.connectionStateCallback != nil {
+		if errors.Is(err, errStreamClosed) {
+			err = nil
+		}
+		m.connectionStateCallback(connected, err)
+	}
+}
+
+// waitStable waits for the given duration, returning early with an error if
+// the event stream ends in the meantime. Any events received are discarded, as
+// the subsequent gather will account for them.
+func (m *monitor) waitStable(duration time.Duration, eventCh <-chan events.Message, errCh <-chan error) error {
+	m.log("Waiting for event stream to stabilise", "duration", duration)
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+
+		case <-m.stop:
+			return errStopped
+
+		case err := <-errCh:
+			if err != nil {
+				return fmt.Errorf("failed to stream events: %w", err)
+			}
+			return errStreamClosed
+
+		case _, ok := <-eventCh:
+			if !ok {
+				return errStreamClosed
+			}
+
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// initialGather performs the first gather after subscribing to events. If a
+// connect timeout is configured, the gather must complete within it.
+func (m *monitor) initialGather() error {
+	ctx := m.ctx
+	if m.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(m.ctx, m.connectTimeout)
+		defer cancel()
+	}
+
+	err := m.refreshAll(ctx)
+	if err != nil {
+		if m.connectTimeout > 0 && m.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s: %w", ErrConnectTimeout, m.connectTimeout, err)
+		}
+		return err
+	}
+
+	m.startup.CompareAndSwap(startupPending, startupComplete)
+	return nil
+}
+
+// refreshAll discards any cached inspections, then gathers all containers. It
+// is used whenever events may have been missed or something may have changed
+// without an event, so the cache can't be trusted.
+func (m *monitor) refreshAll(ctx context.Context) error {
+	m.cached = nil
+	return m.gather(ctx)
 }
 
 // gather retrieves containers, deduplicates, and invokes the callback.
-func (m *monitor) gather() error {
-	containers, err := m.gatherContainers()
+// The given context bounds the retrieval of containers only.
+func (m *monitor) gather(ctx context.Context) error {
+	ctx, span := m.startSpan(ctx, "containuum.gather")
+	defer span.End()
+
+	containers, err := m.gatherContainers(ctx)
 	if err != nil {
-		Log("Failed to refresh containers", "error", err)
-		return fmt.Errorf("failed to refresh containers: %w", err)
+		m.log("Failed to refresh containers", "error", err)
+		err = fmt.Errorf("failed to refresh containers: %w", err)
+		span.Fail(err)
+		return err
+	}
+	span.SetAttributes(Attribute{Key: "containuum.containers", Value: len(containers)})
+
+	if m.incremental {
+		m.known = make(map[string]Container, len(containers))
+		for i := range containers {
+			m.known[containers[i].ID] = containers[i]
+		}
+		m.dirty = nil
+		m.fullResync = false
+	}
+
+	// The context may have a deadline for retrieving the containers, which must
+	// not apply to publishing them; it's only needed as the parent of spans.
+	return m.publish(context.WithoutCancel(ctx), containers)
+}
+
+// update refreshes the containers after events have been received. If
+// incremental updates are enabled, only the containers affected by the events
+// are inspected; otherwise (or if a full resync is needed) all are gathered.
+func (m *monitor) update() error {
+	if !m.incremental || m.fullResync || m.known == nil {
+		return m.gather(m.ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 30*time.Second)
+	defer cancel()
+
+	scope := &filterScope{}
+	m.networkLabels = nil
+
+	m.log("Inspecting containers affected by events", "count", len(m.dirty))
+	for id := range m.dirty {
+		c, err := m.inspect(ctx, id)
+		if err != nil {
+			if !cerrdefs.IsNotFound(err) {
+				m.log("Failed to inspect container, will perform full refresh", "id", id, "error", err)
+				return m.gather(m.ctx)
+			}
+			delete(m.known, id)
+			continue
+		}
+
+		delete(m.known, id)
+		if m.matches(ctx, scope, c) {
+			m.known[c.ID] = c
+		}
+	}
+	m.dirty = nil
+
+	containers := make([]Container, 0, len(m.known))
+	for _, c := range m.known {
+		containers = append(containers, c)
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].ID < containers[j].ID
+	})
+	return m.publish(m.ctx, containers)
+}
+
+// markDirty records the container affected by an event, so that it can be
+// inspected by the next update. If the event can't be attributed to a single
+// container, a full resync is scheduled instead.
+func (m *monitor) markDirty(event events.Message) {
+	id := eventContainerID(event)
+	if id == "" {
+		m.fullResync = true
+		return
+	}
+
+	if m.dirty == nil {
+		m.dirty = make(map[string]struct{})
+	}
+	m.dirty[id] = struct{}{}
+}
+
+// invalidateCached removes the container affected by an event from the inspect
+// cache, so that it is inspected again by the next gather. If the event can't
+// be attributed to a single container, the whole cache is discarded.
+func (m *monitor) invalidateCached(event events.Message) {
+	if m.cached == nil {
+		return
+	}
+
+	id := eventContainerID(event)
+	if id == "" {
+		m.cached = nil
+		return
+	}
+	delete(m.cached, id)
+}
+
+// eventContainerID returns the ID of the container an event relates to, or ""
+// if it doesn't identify one.
+func eventContainerID(event events.Message) string {
+	if event.Type == events.NetworkEventType {
+		return event.Actor.Attributes["container"]
+	}
+	return event.Actor.ID
+}
+
+// unsettle records an event for per-container debouncing, returning true if it
+// was attributed to a container.
+func (m *monitor) unsettle(event events.Message) bool {
+	id := eventContainerID(event)
+	if m.containerDebounce <= 0 || id == "" {
+		return false
+	}
+
+	now := time.Now()
+	if m.unsettled == nil {
+		m.unsettled = make(map[string]unsettledContainer)
+	}
+	u, ok := m.unsettled[id]
+	if !ok {
+		u.first = now
+	}
+	u.last = now
+	m.unsettled[id] = u
+	return true
+}
+
+// settlesAt returns the time at which an unsettled container will settle.
+func (m *monitor) settlesAt(u unsettledContainer) time.Time {
+	settles := u.last.Add(m.containerDebounce)
+	if limit := u.first.Add(m.maxDebounceTime); limit.Before(settles) {
+		return limit
+	}
+	return settles
+}
+
+// nextSettle returns how long until the next unsettled container settles, or
+// false if there are none.
+func (m *monitor) nextSettle() (time.Duration, bool) {
+	var next time.Time
+	for _, u := range m.unsettled {
+		if at := m.settlesAt(u); next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return max(time.Until(next), 0), true
+}
+
+// holdUnsettled replaces any containers that haven't yet settled with the
+// version last published, leaving them out if they weren't published before,
+// and keeping them if they have since gone. Containers that have settled are
+// forgotten.
+func (m *monitor) holdUnsettled(containers []Container) []Container {
+	if m.containerDebounce <= 0 {
+		return containers
+	}
+
+	now := time.Now()
+	for id, u := range m.unsettled {
+		if !now.Before(m.settlesAt(u)) {
+			delete(m.unsettled, id)
+		}
+	}
+
+	result := containers
+	if len(m.unsettled) > 0 {
+		result = make([]Container, 0, len(containers))
+		for i := range containers {
+			if _, ok := m.unsettled[containers[i].ID]; !ok {
+				result = append(result, containers[i])
+			}
+		}
+		for id := range m.unsettled {
+			if c, ok := m.settled[id]; ok {
+				result = append(result, c)
+			}
+		}
+		m.log("Holding back changes to unsettled containers", "count", len(m.unsettled))
 	}
 
+	m.settled = make(map[string]Container, len(result))
+	for i := range result {
+		m.settled[result[i].ID] = result[i]
+	}
+	return result
+}
+
+// publish deduplicates the containers, and invokes the callbacks if they have
+// changed. The context is only used as the parent of any tracing spans.
+func (m *monitor) publish(ctx context.Context, containers []Container) error {
+	actions := m.actions
+	m.actions = nil
+	containers = m.holdUnsettled(containers)
+	m.healthStarting = slices.ContainsFunc(containers, func(c Container) bool {
+		return c.Health == "starting"
+	})
+
 	// Deduplicate
-	currentHash := computeHash(containers)
-	if m.previousHash != nil && currentHash == *m.previousHash {
-		Log("Container state unchanged, not invoking callback")
+	currentHash := computeHashWith(containers, m.hashOptions)
+	if !m.dedupDisabled && m.previousHash != nil && currentHash == *m.previousHash {
+		m.log("Container state unchanged, not invoking callback")
+		m.pending, m.hasPending = nil, false
 		return nil
 	}
 
-	Log("Container state changed, invoking callback", "count", len(containers))
+	if wait := m.minCallbackInterval - time.Since(m.lastCallback); m.minCallbackInterval > 0 && wait > 0 {
+		m.log("Container state changed, delaying callback due to minimum interval", "delay", wait)
+		m.pending, m.hasPending = containers, true
+		m.actions = actions
+		if m.rateTimer != nil {
+			m.rateTimer.Reset(wait)
+		}
+		return nil
+	}
+	m.pending, m.hasPending = nil, false
+	m.lastCallback = time.Now()
+	if m.lastCallbackTime != nil {
+		m.lastCallbackTime.Store(m.lastCallback.UnixNano())
+	}
+	if m.currentCount != nil {
+		m.currentCount.Store(int64(len(containers)))
+	}
+
+	m.log("Container state changed, invoking callback", "count", len(containers))
 	m.previousHash = &currentHash
-	m.callback(containers)
+
+	if m.less != nil {
+		sort.SliceStable(containers, func(i, j int) bool {
+			return m.less(containers[i], containers[j])
+		})
+	}
+
+	added, removed, changed := Diff(m.previousContainers, containers)
+	m.previousContainers = m.tracked(containers)
+
+	if m.removedCallback != nil && len(removed) > 0 {
+		m.log("Containers removed, invoking removed callback", "count", len(removed))
+		m.invoke(ctx, "removed", func() { m.removedCallback(removed) })
+	}
+	if m.syntheticEventCallback != nil {
+		m.invoke(ctx, "synthetic event", func() { m.syntheticEventCallback(syntheticEvents(added, removed, changed)) })
+	}
+	if m.eventCallback != nil {
+		m.invoke(ctx, "event", func() { m.eventCallback(actions, containers) })
+	}
+	m.notify(ctx, containers)
 	return nil
 }
 
+// notify passes the containers to the callbacks. If async callbacks are enabled
+// the containers are placed in the mailbox, replacing any that haven't yet been
+// delivered; otherwise the callbacks are invoked directly.
+func (m *monitor) notify(ctx context.Context, containers []Container) {
+	if m.mailbox == nil {
+		m.invokeCallbacks(ctx, containers)
+		return
+	}
+
+	select {
+	case <-m.mailbox:
+		m.log("Callback still running, replacing undelivered container state")
+	default:
+	}
+	m.mailbox <- containers
+}
+
+// deliverAsync invokes the callbacks for each set of containers placed in the
+// mailbox, until it is closed. Closes done when finished.
+func (m *monitor) deliverAsync(done chan<- struct{}) {
+	defer close(done)
+	for containers := range m.mailbox {
+		if m.ctx.Err() != nil {
+			continue
+		}
+		m.invokeCallbacks(m.ctx, containers)
+	}
+}
+
+// invokeCallbacks calls the main and context callbacks, if set.
+func (m *monitor) invokeCallbacks(ctx context.Context, containers []Container) {
+	if m.callback != nil {
+		m.invoke(ctx, "main", func() { m.callback(containers) })
+	}
+	if m.contextCallback != nil {
+		m.invoke(ctx, "context", func() { m.contextCallback(m.ctx, containers) })
+	}
+}
+
+// invoke calls the given callback. If a callback timeout is set, the callback
+// is run on a separate goroutine, and abandoned if it doesn't return in time.
+func (m *monitor) invoke(ctx context.Context, name string, callback func()) {
+	_, span := m.startSpan(ctx, "containuum.callback", Attribute{Key: "containuum.callback", Value: name})
+	defer span.End()
+
+	if m.callbackTimeout <= 0 {
+		m.call(name, callback)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.call(name, callback)
+	}()
+
+	timer := time.NewTimer(m.callbackTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		m.log("Callback timed out, continuing without waiting for it", "callback", name, "timeout", m.callbackTimeout)
+		span.Fail(fmt.Errorf("callback timed out after %s", m.callbackTimeout))
+	}
+}
+
+// startSpan starts a tracing span with the given name, if a tracer has been
+// configured. Otherwise, it returns a span that does nothing.
+func (m *monitor) startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if m.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return m.tracer.Start(ctx, name, attrs...)
+}
+
+// call calls the given callback. If recoverPanics is set, any panic is
+// recovered and logged instead of taking down the monitor.
+func (m *monitor) call(name string, callback func()) {
+	if m.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				m.log("Recovered from panic in callback", "callback", name, "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+	}
+	callback()
+}
+
+// tracked returns the containers that should be remembered for diffing against
+// future updates, based on the configured trackStates.
+func (m *monitor) tracked(containers []Container) []Container {
+	if len(m.trackStates) == 0 {
+		return containers
+	}
+
+	var result []Container
+	for i := range containers {
+		if slices.Contains(m.trackStates, containers[i].State) {
+			result = append(result, containers[i])
+		}
+	}
+	return result
+}
+
+// syntheticEvents creates Docker-style container events describing the given changes.
+func syntheticEvents(added, removed, changed []Container) []events.Message {
+	now := time.Now()
+	var messages []events.Message
+
+	appendEvents := func(containers []Container, action events.Action) {
+		for _, c := range containers {
+			messages = append(messages, events.Message{
+				Type:   events.ContainerEventType,
+				Action: action,
+				Actor: events.Actor{
+					ID: c.ID,
+					Attributes: map[string]string{
+						"name":  c.Name,
+						"image": c.Image,
+					},
+				},
+				Scope:    "local",
+				Time:     now.Unix(),
+				TimeNano: now.UnixNano(),
+			})
+		}
+	}
+
+	appendEvents(added, events.ActionCreate)
+	appendEvents(changed, events.ActionUpdate)
+	appendEvents(removed, events.ActionDestroy)
+	return messages
+}
+
 // gatherContainers retrieves all containers, applies filters, and returns the matching set.
-func (m *monitor) gatherContainers() ([]Container, error) {
-	ctx, cancel := context.WithTimeout(m.ctx, 30*time.Second)
+func (m *monitor) gatherContainers(ctx context.Context) ([]Container, error) {
+	if m.summaryOnly {
+		return m.gatherSummaries(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	ids, err := m.containerIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &filterScope{}
+	m.networkLabels = nil
+
+	var cache map[string]Container
+	if m.inspectCache {
+		cache = make(map[string]Container, len(ids))
+	}
+
+	var containers []Container
+	for _, id := range ids {
+		c, ok := m.cached[id]
+		if !ok {
+			var err error
+			c, err = m.inspect(ctx, id)
+			if err != nil {
+				if m.pauseOnError && !cerrdefs.IsNotFound(err) {
+					return nil, fmt.Errorf("failed to inspect container %s: %w", id, err)
+				}
+				continue
+			}
+		}
+
+		if cache != nil {
+			cache[id] = c
+		}
+		if m.matches(ctx, scope, c) {
+			containers = append(containers, c)
+		}
+	}
+
+	if cache != nil {
+		m.cached = cache
+	}
+	return containers, nil
+}
+
+// matches determines whether the container passes the filter and, if it does,
+// the async filter. Containers for which the async filter fails don't match.
+// The filter is evaluated within the given scope, which should be shared by
+// all containers in the same gather.
+func (m *monitor) matches(ctx context.Context, scope *filterScope, c Container) bool {
+	if filter := m.filter.Load(); filter != nil && !scope.match(*filter, c) {
+		return false
+	}
+	if m.asyncFilter == nil {
+		return true
+	}
+
+	ok, err := m.asyncFilter(ctx, c)
+	if err != nil {
+		m.log("Async filter failed, skipping container", "id", c.ID, "error", err)
+		return false
+	}
+	return ok
+}
+
+// inspect retrieves a single container and converts it to our model, applying
+// any transform. Failures other than the container not existing are retried up
+// to inspectRetries times, and are logged before being returned.
+func (m *monitor) inspect(ctx context.Context, id string) (Container, error) {
+	inspect, err := m.client.ContainerInspect(ctx, id)
+	for attempt := 1; err != nil && attempt <= m.inspectRetries && !cerrdefs.IsNotFound(err); attempt++ {
+		m.log("Failed to inspect container, retrying", "id", id, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return Container{}, ctx.Err()
+		case <-time.After(time.Duration(attempt) * inspectRetryDelay):
+		}
+		inspect, err = m.client.ContainerInspect(ctx, id)
+	}
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			m.log("Container not found, treating as absent", "id", id)
+		} else {
+			m.log("Failed to inspect container", "id", id, "error", err)
+		}
+		return Container{}, err
+	}
+
+	c := convertContainer(inspect, m.log)
+	if size, ok := m.listedSizes[id]; ok {
+		c.SizeRw, c.SizeRootFs = size.rw, size.rootFs
+	}
+	if m.networkInspector != nil {
+		if err := m.addNetworkLabels(ctx, &c); err != nil {
+			return Container{}, err
+		}
+	}
+	if m.transform != nil {
+		c = m.transform(c)
+	}
+	return c, nil
+}
+
+// addNetworkLabels populates the labels of the container's networks. Each
+// network is only inspected once per refresh. Networks that no longer exist
+// are left without labels.
+func (m *monitor) addNetworkLabels(ctx context.Context, c *Container) error {
+	for i := range c.Networks {
+		id := c.Networks[i].ID
+		if id == "" {
+			continue
+		}
+
+		labels, ok := m.networkLabels[id]
+		if !ok {
+			inspect, err := m.networkInspector.NetworkInspect(ctx, id, network.InspectOptions{})
+			if err != nil && !cerrdefs.IsNotFound(err) {
+				m.log("Failed to inspect network", "id", id, "error", err)
+				return fmt.Errorf("failed to inspect network %s: %w", id, err)
+			}
+
+			labels = inspect.Labels
+			if m.networkLabels == nil {
+				m.networkLabels = make(map[string]map[string]string)
+			}
+			m.networkLabels[id] = labels
+		}
+		c.Networks[i].Labels = labels
+	}
+	return nil
+}
+
+// containerIDs returns the IDs of the containers to inspect. If specific IDs
+// are being watched they are returned directly, otherwise all containers are listed.
+func (m *monitor) containerIDs(ctx context.Context) ([]string, error) {
+	if len(m.watchIDs) > 0 {
+		return m.watchIDs, nil
+	}
+
+	summaries, err := m.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(summaries))
+	for i := range summaries {
+		ids[i] = summaries[i].ID
+	}
+	return ids, nil
+}
+
+// listContainers lists all containers matching the list filters. Unless
+// specific IDs are being watched, the maximum number of containers is enforced.
+func (m *monitor) listContainers(ctx context.Context) ([]container.Summary, error) {
 	summaries, err := m.client.ContainerList(ctx, container.ListOptions{
-		All: true,
+		All:     true,
+		Size:    m.sizes,
+		Filters: m.listFilters,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errListFailed, err)
+	}
+	m.lastListed = time.Now()
+
+	if m.sizes {
+		m.listedSizes = make(map[string]containerSize, len(summaries))
+		for i := range summaries {
+			m.listedSizes[summaries[i].ID] = containerSize{rw: summaries[i].SizeRw, rootFs: summaries[i].SizeRootFs}
+		}
+	}
+
+	if m.maxContainers > 0 && len(m.watchIDs) == 0 && len(summaries) > m.maxContainers {
+		if m.limitPolicy == ContainerLimitError {
+			return nil, fmt.Errorf("%w: listed %d, maximum is %d", ErrTooManyContainers, len(summaries), m.maxContainers)
+		}
+		m.log("Too many containers, ignoring some", "count", len(summaries), "max", m.maxContainers)
+		slices.SortFunc(summaries, func(a, b container.Summary) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		summaries = summaries[:m.maxContainers]
+	}
+	return summaries, nil
+}
+
+// gatherSummaries retrieves all containers using only the summaries returned
+// when listing them, applies filters, and returns the matching set.
+func (m *monitor) gatherSummaries(ctx context.Context) ([]Container, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	summaries, err := m.listContainers(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	scope := &filterScope{}
+
 	var containers []Container
-	for _, summary := range summaries {
-		inspect, err := m.client.ContainerInspect(ctx, summary.ID)
-		if err != nil {
-			Log("Failed to inspect container", "id", summary.ID, "error", err)
+	for i := range summaries {
+		if len(m.watchIDs) > 0 && !m.watched(summaries[i]) {
 			continue
 		}
 
-		c := convertContainer(inspect)
-		if m.filter == nil || m.filter(c) {
+		c := convertSummary(summaries[i])
+		if m.transform != nil {
+			c = m.transform(c)
+		}
+		if m.matches(ctx, scope, c) {
 			containers = append(containers, c)
 		}
 	}
-
 	return containers, nil
 }
 
-// convertContainer converts a Docker API container to our model.
-func convertContainer(inspect container.InspectResponse) Container {
+// watched reports whether the summarised container is one of those given to
+// WithWatchIDs. As when inspecting, these may be full or abbreviated IDs, or
+// names.
+func (m *monitor) watched(summary container.Summary) bool {
+	for _, id := range m.watchIDs {
+		if id != "" && strings.HasPrefix(summary.ID, id) {
+			return true
+		}
+		for _, name := range summary.Names {
+			if strings.TrimPrefix(name, "/") == strings.TrimPrefix(id, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convertSummary converts a Docker API container summary to our model. Only
+// the details included in the summary are populated.
+func convertSummary(summary container.Summary) Container {
 	c := Container{
-		ID:     inspect.ID,
-		Name:   strings.TrimPrefix(inspect.Name, "/"),
-		Image:  inspect.Config.Image,
-		State:  inspect.State.Status,
-		Labels: inspect.Config.Labels,
+		ID:         summary.ID,
+		Image:      summary.Image,
+		ImageID:    summary.ImageID,
+		State:      string(summary.State),
+		Labels:     summary.Labels,
+		SizeRw:     summary.SizeRw,
+		SizeRootFs: summary.SizeRootFs,
+	}
+	if len(summary.Names) > 0 {
+		c.Name = strings.TrimPrefix(summary.Names[0], "/")
+	}
+	if summary.Created != 0 {
+		c.Created = time.Unix(summary.Created, 0).UTC()
+	}
+	return c
+}
+
+// convertContainer converts a Docker API container to our model. Any problems
+// with the container's details are logged using the given function.
+func convertContainer(inspect container.InspectResponse, log func(msg string, keysAndValues ...any)) Container {
+	var c Container
+
+	// Docker always populates the base details and state, but other
+	// implementations of its API (such as Podman) may omit them.
+	if inspect.ContainerJSONBase != nil {
+		c.ID = inspect.ID
+		c.Name = strings.TrimPrefix(inspect.Name, "/")
+		c.ImageID = inspect.Image
+
+		if created, err := time.Parse(time.RFC3339Nano, inspect.Created); err == nil {
+			c.Created = created
+		}
+
+		c.LogPath = inspect.LogPath
+
+		if inspect.SizeRw != nil {
+			c.SizeRw = *inspect.SizeRw
+		}
+		if inspect.SizeRootFs != nil {
+			c.SizeRootFs = *inspect.SizeRootFs
+		}
+
+		if inspect.HostConfig != nil {
+			c.PidMode = string(inspect.HostConfig.PidMode)
+			c.IpcMode = string(inspect.HostConfig.IpcMode)
+			c.RestartPolicy = string(inspect.HostConfig.RestartPolicy.Name)
+		}
+
+		if inspect.State != nil {
+			c.State = inspect.State.Status
+			c.OOMKilled = inspect.State.OOMKilled
+			c.Pid = inspect.State.Pid
+
+			if inspect.State.Health != nil {
+				c.Health = inspect.State.Health.Status
+			}
+		}
+	}
+
+	if inspect.Config != nil {
+		c.Image = inspect.Config.Image
+		c.Labels = inspect.Config.Labels
+		c.Swarm = swarmInfo(inspect.Config.Labels)
+		c.StopSignal = inspect.Config.StopSignal
+
+		if inspect.Config.StopTimeout != nil {
+			c.StopTimeout = *inspect.Config.StopTimeout
+		}
+
+		if len(inspect.Config.Entrypoint) > 0 || len(inspect.Config.Cmd) > 0 {
+			c.Command = make([]string, 0, len(inspect.Config.Entrypoint)+len(inspect.Config.Cmd))
+			c.Command = append(c.Command, inspect.Config.Entrypoint...)
+			c.Command = append(c.Command, inspect.Config.Cmd...)
+		}
 	}
 
 	if inspect.NetworkSettings != nil {
 		for name, network := range inspect.NetworkSettings.Networks {
+			if network == nil {
+				continue
+			}
 			c.Networks = append(c.Networks, Network{
-				Name:       name,
-				ID:         network.NetworkID,
-				IPAddress:  network.IPAddress,
-				IP6Address: network.GlobalIPv6Address,
-				Gateway:    network.Gateway,
-				Aliases:    network.Aliases,
+				Name:         name,
+				ID:           network.NetworkID,
+				IPAddress:    network.IPAddress,
+				IP6Address:   network.GlobalIPv6Address,
+				IPPrefixLen:  network.IPPrefixLen,
+				IP6PrefixLen: network.GlobalIPv6PrefixLen,
+				Gateway:      network.Gateway,
+				Aliases:      network.Aliases,
+				MacAddress:   network.MacAddress,
+				DriverOpts:   network.DriverOpts,
+				Internal:     isInternal(network),
 			})
 		}
+		c.Networks = dedupeNetworks(c.Networks, log)
 	}
 
 	if inspect.NetworkSettings != nil {
@@ -281,7 +1530,7 @@ func convertContainer(inspect container.InspectResponse) Container {
 					HostIP:        binding.HostIP,
 					HostPort:      uint16(hostPortNum),
 					ContainerPort: containerPort,
-					Protocol:      parts[1],
+					Protocol:      strings.ToLower(parts[1]),
 				})
 			}
 		}
@@ -290,11 +1539,82 @@ func convertContainer(inspect container.InspectResponse) Container {
 	return c
 }
 
+// isInternal determines whether the endpoint appears to be on an internal
+// network. Docker doesn't report this for endpoints, but containers on internal
+// networks are given addresses without a gateway.
+func isInternal(endpoint *network.EndpointSettings) bool {
+	hasAddress := endpoint.IPAddress != "" || endpoint.GlobalIPv6Address != ""
+	hasGateway := endpoint.Gateway != "" || endpoint.IPv6Gateway != ""
+	return hasAddress && !hasGateway
+}
+
+// swarmInfo extracts details of the swarm task from the given container labels,
+// or returns nil if the container is not a swarm task.
+func swarmInfo(labels map[string]string) *SwarmInfo {
+	if _, ok := labels["com.docker.swarm.service.id"]; !ok {
+		return nil
+	}
+
+	info := &SwarmInfo{
+		ServiceName: labels["com.docker.swarm.service.name"],
+		NodeID:      labels["com.docker.swarm.node.id"],
+	}
+
+	// Task names are "<service>.<slot>.<task id>" for replicated services, and
+	// "<service>.<node id>.<task id>" for global services.
+	task := strings.TrimPrefix(labels["com.docker.swarm.task.name"], info.ServiceName+".")
+	if slot, _, ok := strings.Cut(task, "."); ok {
+		info.TaskSlot, _ = strconv.Atoi(slot)
+	}
+
+	return info
+}
+
+// dedupeNetworks removes networks that share an ID with another network,
+// keeping the most complete entry. Docker can occasionally report the same
+// network twice, and the duplicate would otherwise cause spurious changes when
+// it appears or disappears. Duplicates are logged using the given function.
+func dedupeNetworks(networks []Network, log func(msg string, keysAndValues ...any)) []Network {
+	if len(networks) < 2 {
+		return networks
+	}
+
+	var result []Network
+	indices := make(map[string]int, len(networks))
+	for _, n := range networks {
+		if n.ID == "" {
+			result = append(result, n)
+			continue
+		}
+
+		i, ok := indices[n.ID]
+		if !ok {
+			indices[n.ID] = len(result)
+			result = append(result, n)
+			continue
+		}
+
+		existing := result[i]
+		if s, e := n.completeness(), existing.completeness(); s > e || (s == e && n.Name < existing.Name) {
+			log("Replacing duplicate network entry", "id", n.ID, "name", n.Name, "duplicate", existing.Name)
+			result[i] = n
+		} else {
+			log("Ignoring duplicate network entry", "id", n.ID, "name", n.Name, "duplicate", existing.Name)
+		}
+	}
+	return result
+}
+
 // computeHash generates a hash of the container list.
 func computeHash(containers []Container) uint64 {
+	return computeHashWith(containers, hashOptions{fields: AllFields})
+}
+
+// computeHashWith generates a hash of the container list, according to the given options.
+func computeHashWith(containers []Container, opts hashOptions) uint64 {
 	var hash uint64
 	for i := range containers {
-		hash ^= containers[i].hash()
+		hash ^= containers[i].hashWith(opts)
 	}
 	return hash
 }
@@ -0,0 +1,609 @@
+package containuum
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertContainer_StopConfig(t *testing.T) {
+	t.Run("populates stop signal and timeout", func(t *testing.T) {
+		timeout := 30
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{StopSignal: "SIGQUIT", StopTimeout: &timeout},
+		}, Log)
+
+		assert.Equal(t, "SIGQUIT", c.StopSignal)
+		assert.Equal(t, 30, c.StopTimeout)
+	})
+
+	t.Run("defaults are empty", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{},
+		}, Log)
+
+		assert.Equal(t, "", c.StopSignal)
+		assert.Equal(t, 0, c.StopTimeout)
+	})
+}
+
+func TestConvertContainer_NamespaceModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		hostConfig *container.HostConfig
+		wantPid    string
+		wantIpc    string
+	}{
+		{
+			name:       "host mode",
+			hostConfig: &container.HostConfig{PidMode: "host", IpcMode: "host"},
+			wantPid:    "host",
+			wantIpc:    "host",
+		},
+		{
+			name:       "container sharing mode",
+			hostConfig: &container.HostConfig{PidMode: "container:abc123", IpcMode: "container:abc123"},
+			wantPid:    "container:abc123",
+			wantIpc:    "container:abc123",
+		},
+		{
+			name:       "default",
+			hostConfig: &container.HostConfig{},
+			wantPid:    "",
+			wantIpc:    "",
+		},
+		{
+			name:       "no host config",
+			hostConfig: nil,
+			wantPid:    "",
+			wantIpc:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := convertContainer(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:         "container1",
+					State:      &container.State{Status: "running"},
+					HostConfig: tt.hostConfig,
+				},
+				Config: &container.Config{},
+			}, Log)
+
+			assert.Equal(t, tt.wantPid, c.PidMode)
+			assert.Equal(t, tt.wantIpc, c.IpcMode)
+		})
+	}
+}
+
+func TestConvertContainer_Command(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *container.Config
+		want   []string
+	}{
+		{
+			name:   "entrypoint and cmd",
+			config: &container.Config{Entrypoint: []string{"/docker-entrypoint.sh"}, Cmd: []string{"nginx", "-g", "daemon off;"}},
+			want:   []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"},
+		},
+		{
+			name:   "cmd only",
+			config: &container.Config{Cmd: []string{"sleep", "30"}},
+			want:   []string{"sleep", "30"},
+		},
+		{
+			name:   "entrypoint only",
+			config: &container.Config{Entrypoint: []string{"/app"}},
+			want:   []string{"/app"},
+		},
+		{
+			name:   "neither",
+			config: &container.Config{},
+			want:   nil,
+		},
+		{
+			name:   "no config",
+			config: nil,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := convertContainer(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: tt.config,
+			}, Log)
+
+			assert.Equal(t, tt.want, c.Command)
+		})
+	}
+}
+
+func TestConvertContainer_RestartPolicy(t *testing.T) {
+	t.Run("populates restart policy", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				State: &container.State{Status: "running"},
+				HostConfig: &container.HostConfig{
+					RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyUnlessStopped},
+				},
+			},
+			Config: &container.Config{},
+		}, Log)
+
+		assert.Equal(t, "unless-stopped", c.RestartPolicy)
+	})
+
+	t.Run("no host config produces empty policy", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{},
+		}, Log)
+
+		assert.Equal(t, "", c.RestartPolicy)
+	})
+}
+
+func TestConvertContainer_Health(t *testing.T) {
+	t.Run("populates health status", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID: "container1",
+				State: &container.State{
+					Status: "running",
+					Health: &container.Health{Status: container.Healthy},
+				},
+			},
+			Config: &container.Config{},
+		}, Log)
+
+		assert.Equal(t, "healthy", c.Health)
+	})
+
+	t.Run("no health check produces empty health", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{},
+		}, Log)
+
+		assert.Equal(t, "", c.Health)
+	})
+}
+
+func TestConvertContainer_OOMKilled(t *testing.T) {
+	c := convertContainer(container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "container1",
+			State: &container.State{Status: "exited", OOMKilled: true},
+		},
+		Config: &container.Config{},
+	}, Log)
+
+	assert.True(t, c.OOMKilled)
+}
+
+func TestConvertContainer_Swarm(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   *SwarmInfo
+	}{
+		{
+			name: "replicated service task",
+			labels: map[string]string{
+				"com.docker.swarm.service.id":   "svc123",
+				"com.docker.swarm.service.name": "web",
+				"com.docker.swarm.task.id":      "task123",
+				"com.docker.swarm.task.name":    "web.3.task123",
+				"com.docker.swarm.node.id":      "node123",
+			},
+			want: &SwarmInfo{ServiceName: "web", TaskSlot: 3, NodeID: "node123"},
+		},
+		{
+			name: "global service task",
+			labels: map[string]string{
+				"com.docker.swarm.service.id":   "svc123",
+				"com.docker.swarm.service.name": "agent",
+				"com.docker.swarm.task.name":    "agent.node123.task123",
+				"com.docker.swarm.node.id":      "node123",
+			},
+			want: &SwarmInfo{ServiceName: "agent", TaskSlot: 0, NodeID: "node123"},
+		},
+		{
+			name:   "non-swarm container",
+			labels: map[string]string{"com.docker.compose.service": "web"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := convertContainer(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Labels: tt.labels},
+			}, Log)
+
+			assert.Equal(t, tt.want, c.Swarm)
+		})
+	}
+}
+
+func TestConvertContainer_ImageID(t *testing.T) {
+	c := convertContainer(container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "container1",
+			Image: "sha256:0123456789abcdef",
+			State: &container.State{Status: "running"},
+		},
+		Config: &container.Config{Image: "nginx:latest"},
+	}, Log)
+
+	assert.Equal(t, "nginx:latest", c.Image)
+	assert.Equal(t, "sha256:0123456789abcdef", c.ImageID)
+}
+
+func TestConvertContainer_Created(t *testing.T) {
+	t.Run("parses creation time", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:      "container1",
+				Created: "2025-06-01T12:34:56.123456789Z",
+				State:   &container.State{Status: "running"},
+			},
+		}, Log)
+
+		assert.Equal(t, time.Date(2025, 6, 1, 12, 34, 56, 123456789, time.UTC), c.Created)
+	})
+
+	t.Run("invalid creation time is left zero", func(t *testing.T) {
+		c := convertContainer(container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:      "container1",
+				Created: "yesterday",
+				State:   &container.State{Status: "running"},
+			},
+		}, Log)
+
+		assert.True(t, c.Created.IsZero())
+	})
+}
+
+func TestConvertContainer_PortProtocols(t *testing.T) {
+	inspect := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "container1"},
+		NetworkSettings: &container.NetworkSettings{
+			NetworkSettingsBase: container.NetworkSettingsBase{
+				Ports: nat.PortMap{
+					"80/tcp":    {{HostIP: "0.0.0.0", HostPort: "8080"}},
+					"53/UDP":    {{HostIP: "0.0.0.0", HostPort: "5353"}},
+					"9899/sctp": {{HostIP: "0.0.0.0", HostPort: "9899"}},
+					"3868/SCTP": {{HostIP: "0.0.0.0", HostPort: "3868"}},
+				},
+			},
+		},
+	}
+
+	c := convertContainer(inspect, Log)
+	assert.ElementsMatch(t, []Port{
+		{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{HostIP: "0.0.0.0", HostPort: 5353, ContainerPort: 53, Protocol: "udp"},
+		{HostIP: "0.0.0.0", HostPort: 9899, ContainerPort: 9899, Protocol: "sctp"},
+		{HostIP: "0.0.0.0", HostPort: 3868, ContainerPort: 3868, Protocol: "sctp"},
+	}, c.Ports)
+}
+
+func TestConvertContainer_InternalNetwork(t *testing.T) {
+	c := convertContainer(container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "container1",
+			State: &container.State{Status: "running"},
+		},
+		Config: &container.Config{},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"frontend": {NetworkID: "net1", IPAddress: "172.18.0.2", Gateway: "172.18.0.1"},
+				"backend":  {NetworkID: "net2", IPAddress: "172.19.0.2"},
+				"none":     {NetworkID: "net3"},
+			},
+		},
+	}, Log)
+
+	internal := map[string]bool{}
+	for _, n := range c.Networks {
+		internal[n.Name] = n.Internal
+	}
+	assert.Equal(t, map[string]bool{"frontend": false, "backend": true, "none": false}, internal)
+}
+
+func TestReconnectConfig_Jittered(t *testing.T) {
+	t.Run("no jitter returns delay unchanged", func(t *testing.T) {
+		r := &reconnectConfig{}
+		assert.Equal(t, 4*time.Second, r.jittered(4*time.Second, func() float64 { return 0.5 }))
+	})
+
+	t.Run("delays fall within expected range", func(t *testing.T) {
+		r := &reconnectConfig{Jitter: 0.5}
+		for _, v := range []float64{0, 0.25, 0.5, 0.75, 0.999} {
+			got := r.jittered(4*time.Second, func() float64 { return v })
+			assert.GreaterOrEqual(t, got, 2*time.Second)
+			assert.LessOrEqual(t, got, 4*time.Second)
+		}
+	})
+
+	t.Run("random value scales removed portion", func(t *testing.T) {
+		r := &reconnectConfig{Jitter: 0.5}
+		assert.Equal(t, 4*time.Second, r.jittered(4*time.Second, func() float64 { return 0 }))
+		assert.Equal(t, 3*time.Second, r.jittered(4*time.Second, func() float64 { return 0.5 }))
+	})
+}
+
+func TestMonitor_ReconnectJitter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		mock.setContainers()
+
+		m := &monitor{
+			ctx:             ctx,
+			client:          mock,
+			callback:        func([]Container) {},
+			debounce:        100 * time.Millisecond,
+			maxDebounceTime: 5 * time.Second,
+			maxIdleTime:     30 * time.Second,
+			reconnect: &reconnectConfig{
+				MinDelay: 4 * time.Second,
+				MaxDelay: 4 * time.Second,
+				Jitter:   0.5,
+			},
+			random: func() float64 { return 1 },
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.run()
+		}()
+
+		synctest.Wait()
+		mock.errCh <- errors.New("stream failed")
+
+		time.Sleep(2*time.Second - time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 1, mock.eventCallCount(), "should not reconnect before jittered delay")
+
+		time.Sleep(time.Millisecond)
+		synctest.Wait()
+		assert.Equal(t, 2, mock.eventCallCount(), "should reconnect after jittered delay")
+
+		cancel()
+		<-errCh
+	})
+}
+
+func TestConvertContainer_NetworkMacAndDriverOpts(t *testing.T) {
+	c := convertContainer(container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "container1",
+			State: &container.State{Status: "running"},
+		},
+		Config: &container.Config{},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"bridge": {
+					NetworkID:  "net1",
+					MacAddress: "02:42:ac:11:00:02",
+					DriverOpts: map[string]string{"com.example.opt": "value"},
+				},
+			},
+		},
+	}, Log)
+
+	if assert.Len(t, c.Networks, 1) {
+		assert.Equal(t, "02:42:ac:11:00:02", c.Networks[0].MacAddress)
+		assert.Equal(t, map[string]string{"com.example.opt": "value"}, c.Networks[0].DriverOpts)
+	}
+}
+
+func TestConvertContainer_NetworkPrefixLen(t *testing.T) {
+	c := convertContainer(container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "container1",
+			State: &container.State{Status: "running"},
+		},
+		Config: &container.Config{},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"bridge": {
+					IPAddress:   "172.17.0.2",
+					IPPrefixLen: 16,
+				},
+			},
+		},
+	}, Log)
+
+	if assert.Len(t, c.Networks, 1) {
+		assert.Equal(t, 16, c.Networks[0].IPPrefixLen)
+		assert.Equal(t, 0, c.Networks[0].IP6PrefixLen)
+	}
+}
+
+func TestConvertContainer_DuplicateNetworks(t *testing.T) {
+	inspect := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    "container1",
+			State: &container.State{Status: "running"},
+		},
+		Config: &container.Config{},
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"frontend": {
+					NetworkID:   "net1",
+					IPAddress:   "172.18.0.2",
+					IPPrefixLen: 16,
+					Gateway:     "172.18.0.1",
+				},
+				"frontend-alias": {
+					NetworkID: "net1",
+				},
+				"backend": {
+					NetworkID: "net2",
+					IPAddress: "172.19.0.2",
+				},
+			},
+		},
+	}
+
+	var hashes []uint64
+	for i := 0; i < 10; i++ {
+		c := convertContainer(inspect, Log)
+		if assert.Len(t, c.Networks, 2) {
+			for _, n := range c.Networks {
+				if n.ID == "net1" {
+					assert.Equal(t, "frontend", n.Name, "most complete entry should be kept")
+					assert.Equal(t, "172.18.0.2", n.IPAddress)
+				}
+			}
+		}
+		hashes = append(hashes, c.hash())
+	}
+
+	for _, h := range hashes {
+		assert.Equal(t, hashes[0], h, "hash should be stable")
+	}
+}
+
+func TestDedupeNetworks(t *testing.T) {
+	t.Run("identical duplicates do not cancel out", func(t *testing.T) {
+		n := Network{Name: "bridge", ID: "net1", IPAddress: "172.17.0.2"}
+		with := Container{ID: "c1", Networks: dedupeNetworks([]Network{n, n}, Log)}
+		without := Container{ID: "c1"}
+
+		assert.Len(t, with.Networks, 1)
+		assert.NotEqual(t, without.hash(), with.hash())
+	})
+
+	t.Run("networks without IDs are kept", func(t *testing.T) {
+		networks := dedupeNetworks([]Network{{Name: "a"}, {Name: "b"}}, Log)
+		assert.Len(t, networks, 2)
+	})
+}
+
+func TestConvertContainer_PartialInspect(t *testing.T) {
+	tests := []struct {
+		name    string
+		inspect container.InspectResponse
+		want    Container
+	}{
+		{
+			name:    "empty",
+			inspect: container.InspectResponse{},
+			want:    Container{},
+		},
+		{
+			name: "without state",
+			inspect: container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:      "container1",
+					Name:    "/test1",
+					LogPath: "/var/lib/docker/containers/container1/container1-json.log",
+				},
+				Config: &container.Config{Image: "nginx:latest"},
+			},
+			want: Container{ID: "container1", Name: "test1", Image: "nginx:latest", LogPath: "/var/lib/docker/containers/container1/container1-json.log"},
+		},
+		{
+			name: "with log path and pid",
+			inspect: container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:      "container1",
+					LogPath: "/var/lib/docker/containers/container1/container1-json.log",
+					State:   &container.State{Status: "running", Pid: 4242},
+				},
+			},
+			want: Container{ID: "container1", State: "running", LogPath: "/var/lib/docker/containers/container1/container1-json.log", Pid: 4242},
+		},
+		{
+			name: "without config",
+			inspect: container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					State: &container.State{Status: "running"},
+				},
+			},
+			want: Container{ID: "container1", State: "running"},
+		},
+		{
+			name: "without config or state",
+			inspect: container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:   "container1",
+					Name: "/test1",
+				},
+			},
+			want: Container{ID: "container1", Name: "test1"},
+		},
+		{
+			name: "without base",
+			inspect: container.InspectResponse{
+				Config: &container.Config{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+			},
+			want: Container{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+		},
+		{
+			name: "with nil network endpoint",
+			inspect: container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{ID: "container1"},
+				NetworkSettings: &container.NetworkSettings{
+					Networks: map[string]*network.EndpointSettings{
+						"podman": nil,
+						"web":    {NetworkID: "net1", IPAddress: "10.88.0.2", Gateway: "10.88.0.1"},
+					},
+				},
+			},
+			want: Container{
+				ID:       "container1",
+				Networks: []Network{{Name: "web", ID: "net1", IPAddress: "10.88.0.2", Gateway: "10.88.0.1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				assert.Equal(t, tt.want, convertContainer(tt.inspect, Log))
+			})
+		})
+	}
+}
@@ -2,11 +2,19 @@ package containuum
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
 )
 
 // Log is the logging function used by the library.
@@ -27,34 +35,192 @@ type DockerClient interface {
 	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
 }
 
+// NetworkInspector is an optional interface that a DockerClient may implement
+// to allow networks to be inspected, as required by WithNetworkLabels. It is
+// separate from DockerClient so that existing implementations don't need to
+// change; the Docker SDK's client implements it.
+type NetworkInspector interface {
+	// NetworkInspect returns detailed information about a network.
+	NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+}
+
 // Option configures the monitor.
 type Option func(*config)
 
 // Callback is invoked when the set of matching containers changes.
+// Callbacks are invoked synchronously, and the monitor waits for them to return
+// before processing further events, unless WithAsyncCallback is used.
 type Callback func(containers []Container)
 
+// ContextCallback is invoked when the set of matching containers changes.
+// The context is cancelled when the monitor stops.
+type ContextCallback func(ctx context.Context, containers []Container)
+
 // config holds the configuration for monitoring.
 type config struct {
-	client              DockerClient
-	filter              Filter
-	debounce            time.Duration
-	maxDebounceTime     time.Duration
-	maxIdleTime         time.Duration
-	enableAutoReconnect bool
-	minReconnectDelay   time.Duration
-	maxReconnectDelay   time.Duration
-	maxReconnectRetries int
+	client                  DockerClient
+	clientOptions           []client.Opt
+	newClient               func(context.Context) (DockerClient, func() error, error)
+	filter                  Filter
+	asyncFilter             func(context.Context, Container) (bool, error)
+	watchIDs                []string
+	scopeEventsToIDs        bool
+	listFilters             filters.Args
+	watchedActions          []string
+	incrementalUpdates      bool
+	inspectRetries          int
+	maxContainers           int
+	containerLimitPolicy    ContainerLimitPolicy
+	inspectCache            bool
+	summaryOnly             bool
+	sizes                   bool
+	recoverPanics           bool
+	tracer                  Tracer
+	networkLabels           bool
+	pauseOnError            bool
+	staleOnListError        time.Duration
+	transform               func(Container) Container
+	removedCallback         Callback
+	contextCallback         ContextCallback
+	syntheticEventCallback  func([]events.Message)
+	eventCallback           func(actions []string, containers []Container)
+	asyncCallback           bool
+	less                    func(a, b Container) bool
+	seedState               []Container
+	seeded                  bool
+	seedFingerprint         *uint64
+	trackStates             []string
+	hashFields              Field
+	dedupDisabled           bool
+	ignoredLabels           []string
+	name                    string
+	eventLogSampling        int
+	eventBuffer             int
+	connectTimeout          time.Duration
+	startupTimeout          time.Duration
+	debounce                time.Duration
+	debounceStrategy        DebounceStrategy
+	containerDebounce       time.Duration
+	maxDebounceTime         time.Duration
+	maxIdleTime             time.Duration
+	healthPollInterval      time.Duration
+	fullResyncInterval      time.Duration
+	minCallbackInterval     time.Duration
+	callbackTimeout         time.Duration
+	eventGapThreshold       time.Duration
+	enableAutoReconnect     bool
+	minReconnectDelay       time.Duration
+	maxReconnectDelay       time.Duration
+	maxReconnectRetries     int
+	reconnectJitter         float64
+	reconnectStableTime     time.Duration
+	reconnectResetAfter     time.Duration
+	connectionStateCallback func(connected bool, err error)
+}
+
+// hashOptions returns the options to use when hashing containers for deduplication.
+func (c *config) hashOptions() hashOptions {
+	opts := hashOptions{fields: c.hashFields}
+	if len(c.ignoredLabels) > 0 {
+		opts.ignoredLabels = make(map[string]struct{}, len(c.ignoredLabels))
+		for _, key := range c.ignoredLabels {
+			opts.ignoredLabels[key] = struct{}{}
+		}
+	}
+	return opts
 }
 
 // defaultConfig returns a config with sensible defaults.
 func defaultConfig() *config {
 	return &config{
-		debounce:        100 * time.Millisecond,
-		maxDebounceTime: 5 * time.Second,
-		maxIdleTime:     30 * time.Second,
+		debounce:            100 * time.Millisecond,
+		maxDebounceTime:     5 * time.Second,
+		maxIdleTime:         30 * time.Second,
+		reconnectResetAfter: time.Minute,
+		less:                func(a, b Container) bool { return a.ID < b.ID },
+		hashFields:          AllFields,
 	}
 }
 
+// validate checks that the config is coherent, returning an error wrapping
+// ErrInvalidOption describing the first problem found.
+func (c *config) validate() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"connect timeout", c.connectTimeout},
+		{"startup timeout", c.startupTimeout},
+		{"debounce", c.debounce},
+		{"per-container debounce", c.containerDebounce},
+		{"max debounce time", c.maxDebounceTime},
+		{"max idle time", c.maxIdleTime},
+		{"health poll interval", c.healthPollInterval},
+		{"full resync interval", c.fullResyncInterval},
+		{"min callback interval", c.minCallbackInterval},
+		{"callback timeout", c.callbackTimeout},
+		{"event gap threshold", c.eventGapThreshold},
+		{"reconnect stable time", c.reconnectStableTime},
+		{"reconnect reset time", c.reconnectResetAfter},
+		{"max staleness", c.staleOnListError},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			return fmt.Errorf("%w: %s must not be negative (got %s)", ErrInvalidOption, d.name, d.value)
+		}
+	}
+
+	if c.maxIdleTime == 0 {
+		return fmt.Errorf("%w: max idle time must be positive", ErrInvalidOption)
+	}
+	if c.maxDebounceTime < c.debounce {
+		return fmt.Errorf("%w: max debounce time (%s) must not be less than debounce (%s)", ErrInvalidOption, c.maxDebounceTime, c.debounce)
+	}
+	if c.debounceStrategy != DebounceTrailing && c.debounceStrategy != DebounceLeading {
+		return fmt.Errorf("%w: unknown debounce strategy %d", ErrInvalidOption, c.debounceStrategy)
+	}
+	for _, action := range c.watchedActions {
+		if !slices.Contains(defaultActions, action) {
+			return fmt.Errorf("%w: unsupported watched action %q", ErrInvalidOption, action)
+		}
+	}
+	if c.summaryOnly && c.networkLabels {
+		return fmt.Errorf("%w: network labels can't be used with summary only mode", ErrInvalidOption)
+	}
+	if c.maxContainers < 0 {
+		return fmt.Errorf("%w: max containers must not be negative (got %d)", ErrInvalidOption, c.maxContainers)
+	}
+	if c.containerLimitPolicy != ContainerLimitError && c.containerLimitPolicy != ContainerLimitTruncate {
+		return fmt.Errorf("%w: unknown container limit policy %d", ErrInvalidOption, c.containerLimitPolicy)
+	}
+	if c.inspectRetries < 0 {
+		return fmt.Errorf("%w: inspect retries must not be negative (got %d)", ErrInvalidOption, c.inspectRetries)
+	}
+	if c.eventBuffer < 0 {
+		return fmt.Errorf("%w: event buffer must not be negative (got %d)", ErrInvalidOption, c.eventBuffer)
+	}
+	if c.eventLogSampling < 0 {
+		return fmt.Errorf("%w: event log sampling must not be negative (got %d)", ErrInvalidOption, c.eventLogSampling)
+	}
+
+	if c.enableAutoReconnect {
+		if c.minReconnectDelay <= 0 {
+			return fmt.Errorf("%w: min reconnect delay must be positive (got %s)", ErrInvalidOption, c.minReconnectDelay)
+		}
+		if c.maxReconnectDelay < c.minReconnectDelay {
+			return fmt.Errorf("%w: max reconnect delay (%s) must not be less than min reconnect delay (%s)", ErrInvalidOption, c.maxReconnectDelay, c.minReconnectDelay)
+		}
+		if c.maxReconnectRetries < 0 {
+			return fmt.Errorf("%w: max reconnect retries must not be negative (got %d)", ErrInvalidOption, c.maxReconnectRetries)
+		}
+		if c.reconnectResetAfter == 0 {
+			return fmt.Errorf("%w: reconnect reset time must be positive", ErrInvalidOption)
+		}
+	}
+
+	return nil
+}
+
 // WithDockerClient sets a custom Docker client.
 // If not provided, a client will be created using default settings.
 func WithDockerClient(client DockerClient) Option {
@@ -63,6 +229,36 @@ func WithDockerClient(client DockerClient) Option {
 	}
 }
 
+// WithClientFactory sets a function used to create Docker clients. It is called
+// when the monitor starts, and again before each reconnection attempt made by
+// WithAutoReconnect, so that a fresh client is used rather than one whose
+// connection may be permanently broken. The returned cleanup function (which
+// may be nil) is called when the client is replaced, and when the monitor
+// stops. If the factory fails when reconnecting, the existing client is kept.
+//
+// The factory takes precedence over WithDockerClient and WithClientOptions.
+func WithClientFactory(factory func(ctx context.Context) (DockerClient, func() error, error)) Option {
+	return func(c *config) {
+		c.newClient = func(ctx context.Context) (DockerClient, func() error, error) {
+			client, cleanup, err := factory(ctx)
+			if err == nil && cleanup == nil {
+				cleanup = func() error { return nil }
+			}
+			return client, cleanup, err
+		}
+	}
+}
+
+// WithClientOptions sets additional options to use when creating the default
+// Docker client, such as client.WithHost or client.WithVersion. They are applied
+// after the defaults (client.FromEnv and client.WithAPIVersionNegotiation), so
+// may override them. Ignored if WithDockerClient or WithClientFactory is used.
+func WithClientOptions(opts ...client.Opt) Option {
+	return func(c *config) {
+		c.clientOptions = opts
+	}
+}
+
 // WithFilter sets the filter for selecting containers.
 // Use All() or Any() to combine multiple filters.
 func WithFilter(filter Filter) Option {
@@ -71,6 +267,445 @@ func WithFilter(filter Filter) Option {
 	}
 }
 
+// WithAsyncFilter sets an additional filter for selecting containers, which may
+// be slow or fail, e.g. because it consults an external service. It is only
+// applied to containers that match the filter set by WithFilter, and is passed
+// a context that is cancelled if the refresh times out. If it returns an
+// error, the error is logged and the container is left out.
+func WithAsyncFilter(filter func(ctx context.Context, c Container) (bool, error)) Option {
+	return func(c *config) {
+		c.asyncFilter = filter
+	}
+}
+
+// WithWatchIDs restricts monitoring to the containers with the given IDs (or
+// names). These containers are inspected directly rather than listing all
+// containers, which is considerably cheaper when only a few are of interest.
+// Containers that don't exist are treated as absent. Any filter is still applied.
+func WithWatchIDs(ids ...string) Option {
+	return func(c *config) {
+		c.watchIDs = ids
+		c.scopeEventsToIDs = false
+	}
+}
+
+// WithContainerIDs restricts monitoring to the containers with the given IDs
+// (or names), as WithWatchIDs does, and also asks Docker to only send events
+// for those containers. This avoids waking up for every event on a busy host.
+//
+// Network events are reported against the network rather than the container,
+// so they are filtered out as well: changes to the networks a container is
+// connected to are only noticed at the next refresh (see WithMaxIdleTime).
+func WithContainerIDs(ids ...string) Option {
+	return func(c *config) {
+		c.watchIDs = ids
+		c.scopeEventsToIDs = true
+	}
+}
+
+// WithListFilters sets filters that are passed to Docker when listing
+// containers, such as filters.Arg("network", "web"). Containers excluded by
+// these filters are never inspected, which is much cheaper than excluding them
+// with WithFilter. Any filter set with WithFilter is applied afterwards.
+//
+// These filters are not applied to containers named with WithWatchIDs, nor to
+// containers inspected because of events when using WithIncrementalUpdates. In
+// those cases, use an equivalent WithFilter as well.
+func WithListFilters(args filters.Args) Option {
+	return func(c *config) {
+		c.listFilters = args
+	}
+}
+
+// WithWatchedActions limits the container and network events that are
+// subscribed to, to those with the given actions (e.g. "start", "die"). This
+// reduces how often the monitor wakes up on busy hosts, but changes caused by
+// other events won't be noticed until the next refresh (see WithMaxIdleTime).
+//
+// Actions must be ones that are subscribed to by default: "create", "start",
+// "restart", "stop", "die", "kill", "pause", "unpause", "rename", "update",
+// "destroy", "health_status", "oom", "connect" and "disconnect". By default,
+// all of them are.
+func WithWatchedActions(actions ...string) Option {
+	return func(c *config) {
+		c.watchedActions = actions
+	}
+}
+
+// WithIncrementalUpdates makes the monitor inspect only the containers that
+// events relate to, rather than listing and inspecting every container whenever
+// an event is received. This greatly reduces the load on the Docker daemon on
+// hosts with many containers. A full refresh is still performed when connecting
+// or reconnecting, when the idle time is exceeded, when RefreshNow is called,
+// and for events that can't be attributed to a single container.
+//
+// Has no effect if WithWatchIDs is used, as only the watched containers are
+// inspected anyway.
+func WithIncrementalUpdates() Option {
+	return func(c *config) {
+		c.incrementalUpdates = true
+	}
+}
+
+// WithInspectRetries sets how many times inspecting a container is retried if
+// it fails, with a short backoff between attempts, before the container is
+// skipped. This prevents transient errors from making a container disappear
+// from the callback for a cycle. Containers that no longer exist are not
+// retried. Default: 0 (no retries).
+func WithInspectRetries(retries int) Option {
+	return func(c *config) {
+		c.inspectRetries = retries
+	}
+}
+
+// ContainerLimitPolicy controls what happens when more containers are listed
+// than allowed by WithMaxContainers.
+type ContainerLimitPolicy int
+
+const (
+	// ContainerLimitError fails the refresh with an error wrapping
+	// ErrTooManyContainers.
+	ContainerLimitError ContainerLimitPolicy = iota
+
+	// ContainerLimitTruncate logs a warning, and only inspects the allowed
+	// number of containers. The containers are sorted by ID before being
+	// truncated, so the same ones are kept on each refresh.
+	ContainerLimitTruncate
+)
+
+// WithMaxContainers limits how many containers are inspected on each refresh,
+// protecting against excessive memory use on hosts with huge numbers of
+// containers. The limit applies to the containers listed by Docker, after any
+// list filters (see WithListFilters) but before any Filter, as the containers
+// must be inspected to apply the latter. It doesn't apply to WithWatchIDs.
+//
+// The policy determines what happens if the limit is exceeded. With
+// WithIncrementalUpdates, the limit is only checked on full refreshes.
+// Default: no limit.
+func WithMaxContainers(n int, policy ContainerLimitPolicy) Option {
+	return func(c *config) {
+		c.maxContainers = n
+		c.containerLimitPolicy = policy
+	}
+}
+
+// WithInspectCache caches the details of each container between refreshes, and
+// only inspects containers again if an event has been received for them. All
+// containers are still listed on every refresh, so new and removed containers
+// are noticed. The cache is discarded whenever events may have been missed,
+// i.e. on (re)connection, when the idle time is exceeded, at each full resync
+// interval, and when RefreshNow is called.
+//
+// This is a lighter alternative to WithIncrementalUpdates. It has no effect
+// when used with WithWatchIDs.
+func WithInspectCache() Option {
+	return func(c *config) {
+		c.inspectCache = true
+	}
+}
+
+// WithSummaryOnly builds containers from the summaries returned when listing
+// them, rather than inspecting each one. This greatly reduces the number of
+// calls made to Docker, at the cost of fidelity: only the ID, Name, Image,
+// ImageID, State, Created and Labels fields are populated. All other fields
+// (including Networks, Ports and Health) are left empty, and filters that
+// depend on them won't match as expected.
+//
+// WithIncrementalUpdates and WithInspectCache have no effect with this option,
+// and it can't be used with WithNetworkLabels.
+func WithSummaryOnly() Option {
+	return func(c *config) {
+		c.summaryOnly = true
+	}
+}
+
+// WithSizes populates the SizeRw and SizeRootFs fields of containers, by asking
+// Docker to calculate them when listing containers. This can be slow, as Docker
+// has to walk each container's filesystem. Sizes change as containers write to
+// disk, so the callback may be invoked more often; use WithHashFields to
+// exclude FieldSize from deduplication if that isn't wanted.
+//
+// With WithInspectCache or WithIncrementalUpdates, sizes are only updated when
+// containers are inspected again. Sizes aren't available with WithWatchIDs.
+func WithSizes() Option {
+	return func(c *config) {
+		c.sizes = true
+	}
+}
+
+// WithNetworkLabels populates the labels of each network a container is
+// connected to, which aren't included in the container's details. Each
+// distinct network is inspected once per refresh. As network labels can't be
+// changed after a network is created, this doesn't watch for changes.
+//
+// The Docker client must implement NetworkInspector; if it doesn't, an error
+// wrapping ErrInvalidOption is returned when the monitor starts.
+func WithNetworkLabels() Option {
+	return func(c *config) {
+		c.networkLabels = true
+	}
+}
+
+// WithPauseOnError keeps the monitor running with the last known state if
+// refreshing the containers fails, rather than returning an error. The
+// callbacks aren't invoked until the containers can be refreshed again, which
+// is retried after the next event or when the idle time (see WithMaxIdleTime)
+// is exceeded. Calls to Monitor.RefreshNow still return the error.
+//
+// With this option, a container that can't be inspected (for any reason other
+// than it no longer existing) causes the whole refresh to fail, instead of the
+// container being left out and appearing to have been removed.
+//
+// Errors from the event stream, and failures during the initial refresh, are
+// still returned. Use WithAutoReconnect to recover from them: the last known
+// state is kept across reconnections, so callbacks are only invoked again
+// once the containers have actually changed.
+func WithPauseOnError() Option {
+	return func(c *config) {
+		c.pauseOnError = true
+	}
+}
+
+// WithStaleOnListError keeps the monitor running with the last known state if
+// listing containers fails, as long as the last successful list was no more
+// than maxStaleness ago. This smooths over brief outages, such as while the
+// Docker daemon restarts, without hiding longer ones: once maxStaleness has
+// passed, the error is returned as normal. The callbacks aren't invoked until
+// the containers can be listed again. Calls to Monitor.RefreshNow still return
+// the error.
+//
+// Unlike WithPauseOnError, only failures to list containers are covered, and
+// only for a limited time.
+func WithStaleOnListError(maxStaleness time.Duration) Option {
+	return func(c *config) {
+		c.staleOnListError = maxStaleness
+	}
+}
+
+// WithContainerTransform sets a function that is applied to each container
+// before it is filtered, deduplicated and passed to callbacks. This can be used
+// to redact or normalise fields; stripping volatile fields will also prevent
+// changes to them from triggering callbacks.
+func WithContainerTransform(transform func(Container) Container) Option {
+	return func(c *config) {
+		c.transform = transform
+	}
+}
+
+// WithContextCallback sets a callback that is invoked with a context when the
+// set of matching containers changes, in addition to the callback passed to
+// Run or New (which may be nil if only this callback is required). The context
+// is cancelled when the monitor stops (either because the context passed to Run
+// or Start is cancelled, or the context passed to Stop is done before the
+// monitor has finished), allowing long-running work such as I/O to be aborted.
+//
+// Like other callbacks, it is invoked synchronously: the monitor waits for it
+// to return before processing further events.
+func WithContextCallback(callback ContextCallback) Option {
+	return func(c *config) {
+		c.contextCallback = callback
+	}
+}
+
+// WithEventCallback sets a callback that is invoked when the set of matching
+// containers changes, along with the distinct actions (e.g. "start", "die") of
+// the Docker events that led to the change, in the order they were first seen.
+// As events are debounced, several actions may be reported at once.
+//
+// Actions are those of any events received since the previous refresh, and may
+// relate to containers that don't match the filter. They are empty if no events
+// were received, such as for the initial refresh or one caused by
+// WithMaxIdleTime.
+func WithEventCallback(callback func(actions []string, containers []Container)) Option {
+	return func(c *config) {
+		c.eventCallback = callback
+	}
+}
+
+// WithAsyncCallback causes the callback passed to Run or New, and any context
+// callback, to be invoked on a separate goroutine so that a slow callback does
+// not block event processing. Only the latest set of containers is delivered:
+// if the state changes again while a callback is still running, any
+// intermediate state that has not yet been delivered is dropped.
+//
+// Removed and synthetic event callbacks are still invoked synchronously, as
+// they describe individual changes that would be lost if coalesced.
+func WithAsyncCallback() Option {
+	return func(c *config) {
+		c.asyncCallback = true
+	}
+}
+
+// WithRecoverCallbackPanics recovers from any panic in a callback, logging it
+// along with a stack trace and carrying on monitoring as if the callback had
+// returned normally. This applies to all callbacks invoked with container
+// state, including removed and event callbacks.
+//
+// By default, a panicking callback crashes the program, which makes bugs
+// harder to miss.
+func WithRecoverCallbackPanics() Option {
+	return func(c *config) {
+		c.recoverPanics = true
+	}
+}
+
+// WithTracer records a span using the given tracer for each time containers
+// are gathered from Docker, with the number of matching containers as the
+// "containuum.containers" attribute. Each callback invocation is recorded as a
+// child span. To use OpenTelemetry, see the otelcontainuum package.
+// Default: no tracing.
+func WithTracer(tracer Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithRemovedCallback sets a callback that is invoked with the full details of
+// any containers that have disappeared since the previous callback, either
+// because they were destroyed or because they no longer match the filter.
+// Containers are tracked by ID, so a renamed container is not considered removed.
+func WithRemovedCallback(callback Callback) Option {
+	return func(c *config) {
+		c.removedCallback = callback
+	}
+}
+
+// WithSyntheticEventCallback sets a callback that is invoked with Docker-style
+// container events describing how the set of containers has changed: a "create"
+// event for each container that has been added, "update" for each that has
+// changed, and "destroy" for each that has been removed. This allows code that
+// consumes Docker events to be reused. Containers are compared after filtering,
+// so a container that stops matching the filter produces a "destroy" event.
+// The initial set of containers is reported as "create" events.
+func WithSyntheticEventCallback(callback func([]events.Message)) Option {
+	return func(c *config) {
+		c.syntheticEventCallback = callback
+	}
+}
+
+// WithTrackStates limits which containers are remembered between updates for
+// the purposes of WithRemovedCallback and WithSyntheticEventCallback to those in
+// the given states. Containers in other states are still passed to callbacks,
+// but won't be reported as removed, and will be reported as added whenever the
+// set of containers changes. This bounds memory use on hosts with many
+// short-lived containers. By default, containers in all states are tracked.
+func WithTrackStates(states ...string) Option {
+	return func(c *config) {
+		c.trackStates = states
+	}
+}
+
+// WithDedupDisabled invokes the callback every time the containers are
+// gathered (after events, periodic polls, RefreshNow and so on), even if
+// nothing has changed. This is useful if the callback is cheap and performs
+// its own change detection. WithHashFields has no effect with this option.
+func WithDedupDisabled() Option {
+	return func(c *config) {
+		c.dedupDisabled = true
+	}
+}
+
+// WithHashFields sets which fields of Container are considered when
+// deduplicating. If only fields that are not included change, the callback is
+// not invoked. For example, WithHashFields(FieldID, FieldState, FieldPorts)
+// ignores changes to labels, networks, and so on.
+// Default is AllFields.
+func WithHashFields(fields ...Field) Option {
+	return func(c *config) {
+		c.hashFields = 0
+		for _, f := range fields {
+			c.hashFields |= f
+		}
+	}
+}
+
+// WithSetIdentityDedup only invokes the callback when the set of matching
+// containers changes, i.e. when a container starts or stops matching the
+// filter. Changes to the details of containers that already matched, such as
+// their labels or state, are ignored. It is equivalent to
+// WithHashFields(FieldID), and overrides any earlier WithHashFields option.
+func WithSetIdentityDedup() Option {
+	return WithHashFields(FieldID)
+}
+
+// WithIgnoredLabels sets label keys that are not considered when
+// deduplicating. The labels are still included in containers passed to
+// callbacks, but changes to them alone won't cause callbacks to be invoked.
+// This is useful for volatile labels such as com.docker.compose.config-hash.
+func WithIgnoredLabels(keys ...string) Option {
+	return func(c *config) {
+		c.ignoredLabels = keys
+	}
+}
+
+// WithSort sets the order in which containers are passed to callbacks.
+// The less function should report whether a sorts before b.
+// Default is to sort by container ID.
+func WithSort(less func(a, b Container) bool) Option {
+	return func(c *config) {
+		c.less = less
+	}
+}
+
+// WithSeedState primes the monitor with a previously seen set of containers,
+// such as the last state passed to the callback before the process restarted.
+// The initial callback is then only invoked if the current containers differ
+// from the seed, using the same comparison as normal deduplication. The seed
+// should be a set of containers as passed to the callback, i.e. after any
+// filter and transform have been applied.
+//
+// The seed is also used as the previous state for WithRemovedCallback and
+// WithSyntheticEventCallback, so only changes since the seed are reported.
+func WithSeedState(containers []Container) Option {
+	return func(c *config) {
+		c.seedState = containers
+		c.seeded = true
+		c.seedFingerprint = nil
+	}
+}
+
+// WithSeedFingerprint primes the monitor with the Fingerprint of a previously
+// seen set of containers. Like WithSeedState, the initial callback is then only
+// invoked if the current containers differ, but only the fingerprint needs to
+// be persisted. As the containers themselves aren't known, every container is
+// treated as added by WithSyntheticEventCallback, and WithRemovedCallback isn't
+// invoked for containers that were removed before the monitor started.
+//
+// The fingerprint is only compared correctly if WithHashFields and
+// WithIgnoredLabels aren't used; otherwise the initial callback is always
+// invoked. If both WithSeedState and WithSeedFingerprint are used, the last
+// one applies.
+func WithSeedFingerprint(fingerprint uint64) Option {
+	return func(c *config) {
+		c.seedState = nil
+		c.seeded = false
+		c.seedFingerprint = &fingerprint
+	}
+}
+
+// WithConnectTimeout sets the maximum time allowed for the initial connection
+// to Docker: subscribing to events and retrieving the first set of containers.
+// If it is exceeded, an error wrapping ErrConnectTimeout is returned (or, with
+// WithAutoReconnect, the connection is retried). This is separate from the
+// timeout applied to each subsequent refresh. Default: no timeout.
+func WithConnectTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.connectTimeout = timeout
+	}
+}
+
+// WithStartupTimeout sets the maximum time allowed between starting the monitor
+// and the initial set of containers being retrieved and passed to the callback.
+// If it is exceeded, the monitor stops and an error wrapping ErrStartupTimeout
+// is returned. Unlike WithConnectTimeout, this covers the whole of startup,
+// including any reconnection attempts made by WithAutoReconnect, and is never
+// applied again once startup has completed. Default: no timeout.
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.startupTimeout = timeout
+	}
+}
+
 // WithDebounce sets the debounce duration for coalescing rapid events.
 // Default is 100ms.
 func WithDebounce(d time.Duration) Option {
@@ -79,6 +714,52 @@ func WithDebounce(d time.Duration) Option {
 	}
 }
 
+// DebounceStrategy controls when containers are refreshed in response to a
+// burst of events.
+type DebounceStrategy int
+
+const (
+	// DebounceTrailing refreshes the containers once events stop arriving for
+	// the debounce duration. This is the default.
+	DebounceTrailing DebounceStrategy = iota
+
+	// DebounceLeading refreshes the containers as soon as the first event
+	// arrives, then waits until events stop arriving for the debounce duration.
+	// If any further events arrived in that time, the containers are refreshed
+	// again at the end.
+	DebounceLeading
+)
+
+// WithDebounceStrategy sets when containers are refreshed in response to
+// events. With DebounceLeading, changes are reported with lower latency, at
+// the cost of up to two refreshes per burst of events. In either case, a burst
+// lasting longer than the max debounce time (see WithMaxDebounceTime) is
+// refreshed at that point, and the next event starts a new burst.
+// Default is DebounceTrailing.
+func WithDebounceStrategy(strategy DebounceStrategy) Option {
+	return func(c *config) {
+		c.debounceStrategy = strategy
+	}
+}
+
+// WithPerContainerDebounce debounces changes to each container independently,
+// so that one container with a constant stream of events doesn't delay updates
+// to the others. A container has settled once there have been no events for it
+// for the given duration, or it has been unsettled for the max debounce time
+// (see WithMaxDebounceTime). Until then, callbacks are passed the container as
+// it was last passed to them: changes to it are held back, it is left out if
+// it is new, and kept if it has been removed. The callbacks are invoked again
+// once it settles.
+//
+// Events are still debounced as a whole using WithDebounce, which should be
+// set lower than d. Events that can't be attributed to a single container
+// aren't debounced per container. Default is disabled.
+func WithPerContainerDebounce(d time.Duration) Option {
+	return func(c *config) {
+		c.containerDebounce = d
+	}
+}
+
 // WithMaxDebounceTime sets the maximum time to wait when debouncing.
 // This prevents indefinite delays when events keep arriving.
 // Default is 5 seconds.
@@ -88,6 +769,31 @@ func WithMaxDebounceTime(d time.Duration) Option {
 	}
 }
 
+// WithMinCallbackInterval sets the minimum time between invocations of the
+// callbacks. If the containers change again sooner, the callbacks are invoked
+// once the interval has elapsed, with the latest state; any intermediate states
+// are skipped. Unlike WithDebounce, the interval is not extended by further
+// events, so this acts as a fixed rate limit. Default: 0 (no limit).
+//
+// A refresh requested with RefreshNow is also subject to this limit, and may
+// return before the callbacks have been invoked.
+func WithMinCallbackInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.minCallbackInterval = interval
+	}
+}
+
+// WithCallbackTimeout sets the maximum time to wait for each callback to
+// return. Callbacks are run on a separate goroutine; if one doesn't return in
+// time, a warning is logged and monitoring continues without it. The goroutine
+// is abandoned rather than stopped, so a stuck callback may still be running
+// when the next one is invoked. Default: 0 (wait indefinitely).
+func WithCallbackTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.callbackTimeout = timeout
+	}
+}
+
 // WithMaxIdleTime sets the maximum time to wait before polling for changes.
 // Default is 30 seconds.
 func WithMaxIdleTime(d time.Duration) Option {
@@ -96,10 +802,90 @@ func WithMaxIdleTime(d time.Duration) Option {
 	}
 }
 
-// WithAutoReconnect enables automatic reconnection on event stream errors.
+// WithHealthPollInterval polls for changes at the given interval while any
+// matching container's health check is still "starting", so that it becoming
+// healthy or unhealthy is noticed promptly even if the event is missed or
+// delayed. Once no containers are starting, polling falls back to
+// WithMaxIdleTime. Default: 0 (disabled).
+func WithHealthPollInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.healthPollInterval = interval
+	}
+}
+
+// WithName sets a name for the monitor, which is included in all of its log
+// messages under the "monitor" key. This distinguishes the logs of multiple
+// monitors running in the same process.
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithEventBuffer reads events from Docker as soon as they arrive, buffering up
+// to n of them while containers are being refreshed, rather than leaving them
+// queued in the Docker client. This keeps the event stream flowing during
+// bursts of events.
+//
+// If the buffer fills up, further events are dropped rather than blocking the
+// stream. As the effects of dropped events can't be known, all containers are
+// refreshed once the monitor catches up. Default is 0 (no buffering).
+func WithEventBuffer(n int) Option {
+	return func(c *config) {
+		c.eventBuffer = n
+	}
+}
+
+// WithEventLogSampling reduces how often received Docker events are logged,
+// logging only the first of every n events. This prevents logs being flooded
+// during event storms. Each logged event includes the total number of events
+// received. Default is 1 (every event is logged).
+func WithEventLogSampling(every int) Option {
+	return func(c *config) {
+		c.eventLogSampling = every
+	}
+}
+
+// WithFullResyncInterval sets an interval at which all containers are
+// refreshed, regardless of whether events are being received. Unlike
+// WithMaxIdleTime, the interval is not reset by events, so this guards against
+// missed events even on busy hosts, and when using WithIncrementalUpdates.
+// Default: 0 (disabled).
+func WithFullResyncInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.fullResyncInterval = interval
+	}
+}
+
+// WithEventGapThreshold enables detection of missed events. If an event is
+// received more than threshold after Docker reports it occurred, the event
+// stream is assumed to have stalled (for example, because of a misbehaving
+// proxy) and events may have been lost, so all containers are refreshed
+// immediately. The threshold should allow for any clock skew between the host
+// and the Docker daemon. Default: 0 (disabled).
+//
+// Periods where no events are received at all are handled by WithMaxIdleTime,
+// and Monitor.LastEventTime can be used to check the stream is alive.
+func WithEventGapThreshold(threshold time.Duration) Option {
+	return func(c *config) {
+		c.eventGapThreshold = threshold
+	}
+}
+
+// WithAutoReconnect enables automatic reconnection when the event stream ends,
+// whether it fails with an error or is closed cleanly (e.g. by a daemon restart).
+// Without this option, an error is returned from Run if the stream fails, and
+// nil is returned if it is closed cleanly.
 // Uses exponential backoff starting at minDelay, doubling up to maxDelay.
-// maxRetries of 0 means retry forever, otherwise stop after that many attempts.
+// maxRetries of 0 means retry forever, otherwise stop after that many attempts
+// and return an error wrapping ErrMaxRetriesExceeded.
 // On successful reconnection, containers will be refreshed.
+//
+// The default Docker client is recreated after every three consecutive failed
+// attempts, in case the connection it uses has been permanently broken (e.g. by
+// the daemon's socket being replaced during an upgrade). A client provided with
+// WithDockerClient is never recreated; use WithClientFactory to create a new
+// client before every attempt.
 func WithAutoReconnect(minDelay, maxDelay time.Duration, maxRetries int) Option {
 	return func(c *config) {
 		c.enableAutoReconnect = true
@@ -109,61 +895,499 @@ func WithAutoReconnect(minDelay, maxDelay time.Duration, maxRetries int) Option
 	}
 }
 
+// WithReconnectJitter randomises each reconnection delay to avoid multiple
+// monitors reconnecting in lockstep. The fraction determines how much of the
+// delay may be removed: a fraction of 0.5 results in delays randomly chosen
+// between half and all of the normal backoff delay. Values are clamped to the
+// range 0-1. Has no effect unless WithAutoReconnect is also used.
+func WithReconnectJitter(fraction float64) Option {
+	return func(c *config) {
+		c.reconnectJitter = min(max(fraction, 0), 1)
+	}
+}
+
+// WithReconnectStableTime requires a reconnected event stream to stay open for
+// the given duration before containers are refreshed. If the stream drops again
+// within that time, no refresh is performed and the monitor goes straight back
+// to reconnecting. This prevents a flapping stream from causing a storm of
+// refreshes. Has no effect unless WithAutoReconnect is also used. Default: 0
+// (refresh immediately on reconnection).
+func WithReconnectStableTime(duration time.Duration) Option {
+	return func(c *config) {
+		c.reconnectStableTime = duration
+	}
+}
+
+// WithReconnectResetAfter sets how long a connection to the event stream must
+// last to be considered healthy. When a healthy connection is lost, the
+// reconnection back-off and retry count are reset, as if it were the first
+// disconnection. Must be positive if WithAutoReconnect is used, as otherwise
+// every connection would be healthy and the retries would never run out. Has no
+// effect unless WithAutoReconnect is also used. Default: 1 minute.
+func WithReconnectResetAfter(duration time.Duration) Option {
+	return func(c *config) {
+		c.reconnectResetAfter = duration
+	}
+}
+
+// WithConnectionStateCallback sets a callback that is invoked when the
+// connection to the Docker event stream changes state. It is called with
+// connected=true once the stream has been subscribed to and the initial
+// containers retrieved, and with connected=false and the cause when the stream
+// ends (other than by the context being cancelled). If Docker closes the stream
+// cleanly, the callback is called with connected=false and a nil error. This is
+// most useful in combination with WithAutoReconnect, to report when the monitor
+// is degraded.
+func WithConnectionStateCallback(callback func(connected bool, err error)) Option {
+	return func(c *config) {
+		c.connectionStateCallback = callback
+	}
+}
+
 // Filter is a function that determines whether a container should be included.
 type Filter func(Container) bool
 
 // All returns a filter that matches if all given filters match (AND).
 // Returns true if the filter list is empty.
 func All(filters ...Filter) Filter {
-	return func(c Container) bool {
+	return combined(func(s *filterScope, c Container) bool {
 		for _, filter := range filters {
-			if !filter(c) {
+			if !s.match(filter, c) {
 				return false
 			}
 		}
 		return true
-	}
+	}, "All", filters...)
 }
 
 // Any returns a filter that matches if any given filter matches (OR).
 // Returns false if the filter list is empty.
 func Any(filters ...Filter) Filter {
-	return func(c Container) bool {
+	return combined(func(s *filterScope, c Container) bool {
 		for _, filter := range filters {
-			if filter(c) {
+			if s.match(filter, c) {
 				return true
 			}
 		}
 		return false
+	}, "Any", filters...)
+}
+
+// filterScope holds the state shared by filters while they are evaluated
+// during a single gather. A new scope is created by the monitor for each
+// gather, so nothing is retained between gathers or shared between monitors.
+type filterScope struct {
+	mu    sync.Mutex
+	memos map[*filterInfo]map[uint64]bool
+}
+
+// match evaluates the filter within the scope. A nil scope is valid, and is
+// used when filters are called directly.
+func (s *filterScope) match(filter Filter, c Container) bool {
+	if info := lookup(filter); info != nil {
+		return info.match(s, c)
+	}
+	return filter(c)
+}
+
+// memo returns the result previously stored for the given filter and key.
+func (s *filterScope) memo(filter *filterInfo, key uint64) (result, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok = s.memos[filter][key]
+	return result, ok
+}
+
+// remember stores the result of the given filter for the given key.
+func (s *filterScope) remember(filter *filterInfo, key uint64, result bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.memos == nil {
+		s.memos = make(map[*filterInfo]map[uint64]bool)
+	}
+	if s.memos[filter] == nil {
+		s.memos[filter] = make(map[uint64]bool)
+	}
+	s.memos[filter][key] = result
+}
+
+// Memoize returns a filter that caches the results of the given filter for
+// the duration of a single gather, keyed by the container's hash. This is
+// useful for expensive filters that appear multiple times in a filter tree.
+// The given filter must be deterministic, i.e. always give the same result
+// for the same container. Results are not cached when the filter is called
+// directly, outside of a gather.
+func Memoize(filter Filter) Filter {
+	memoized := &filterInfo{name: "Memoize", children: []Filter{filter}}
+	memoized.match = func(s *filterScope, c Container) bool {
+		if s == nil {
+			return filter(c)
+		}
+
+		key := c.hash()
+		if result, ok := s.memo(memoized, key); ok {
+			return result
+		}
+
+		result := s.match(filter, c)
+		s.remember(memoized, key, result)
+		return result
 	}
+	return register(memoized)
 }
 
 // Not returns a filter that inverts the result of the given filter.
 func Not(filter Filter) Filter {
-	return func(c Container) bool {
-		return !filter(c)
-	}
+	return combined(func(s *filterScope, c Container) bool {
+		return !s.match(filter, c)
+	}, "Not", filter)
 }
 
 // LabelExists returns a filter that matches containers with the given label key.
 func LabelExists(key string) Filter {
-	return func(c Container) bool {
+	return described(func(c Container) bool {
 		_, exists := c.Labels[key]
 		return exists
-	}
+	}, "LabelExists", key)
 }
 
 // LabelEquals returns a filter that matches containers where
 // the given label equals the given value.
 func LabelEquals(key, value string) Filter {
-	return func(c Container) bool {
+	return described(func(c Container) bool {
 		return c.Labels[key] == value
+	}, "LabelEquals", key, value)
+}
+
+// LabelValueIn returns a filter that matches containers with the given label
+// set to any of the given values. Containers without the label never match.
+func LabelValueIn(key string, values ...string) Filter {
+	set := make(map[string]struct{}, len(values))
+	args := []any{key}
+	for _, value := range values {
+		set[value] = struct{}{}
+		args = append(args, value)
 	}
+
+	return described(func(c Container) bool {
+		value, ok := c.Labels[key]
+		if !ok {
+			return false
+		}
+		_, ok = set[value]
+		return ok
+	}, "LabelValueIn", args...)
+}
+
+// LabelPrefixExists returns a filter that matches containers with at least one
+// label whose key starts with the given prefix (e.g., "traefik.").
+func LabelPrefixExists(prefix string) Filter {
+	return described(func(c Container) bool {
+		return hasLabelWithPrefix(c, prefix)
+	}, "LabelPrefixExists", prefix)
 }
 
 // StateEquals returns a filter that matches containers in the given state.
 func StateEquals(state string) Filter {
-	return func(c Container) bool {
+	return described(func(c Container) bool {
 		return c.State == state
+	}, "StateEquals", state)
+}
+
+// StateIn returns a filter that matches containers in any of the given states.
+func StateIn(states ...string) Filter {
+	args := make([]any, 0, len(states))
+	for _, state := range states {
+		args = append(args, state)
+	}
+
+	return described(func(c Container) bool {
+		return slices.Contains(states, c.State)
+	}, "StateIn", args...)
+}
+
+// Healthy returns a filter that matches running containers that are passing
+// their health check, or that don't have a health check. Use StrictlyHealthy to
+// exclude containers without a health check.
+func Healthy() Filter {
+	return described(func(c Container) bool {
+		return c.State == StateRunning && (c.Health == "" || c.Health == "healthy")
+	}, "Healthy")
+}
+
+// StrictlyHealthy returns a filter that matches running containers that are
+// passing their health check. Containers without a health check never match.
+func StrictlyHealthy() Filter {
+	return described(func(c Container) bool {
+		return c.State == StateRunning && c.Health == "healthy"
+	}, "StrictlyHealthy")
+}
+
+// CreatedBefore returns a filter that matches containers created before the
+// given time. Containers with an unknown creation time never match.
+func CreatedBefore(t time.Time) Filter {
+	return described(func(c Container) bool {
+		return !c.Created.IsZero() && c.Created.Before(t)
+	}, "CreatedBefore", t)
+}
+
+// OlderThan returns a filter that matches containers created more than the
+// given duration ago. Containers with an unknown creation time never match.
+//
+// Unlike other filters, the result depends on the current time when the filter
+// is evaluated, which only happens when containers are refreshed. A container
+// that becomes old enough won't start matching until the next refresh, which
+// may be up to the max idle time (see WithMaxIdleTime) if there are no events.
+func OlderThan(d time.Duration) Filter {
+	return described(func(c Container) bool {
+		return !c.Created.IsZero() && time.Since(c.Created) > d
+	}, "OlderThan", d)
+}
+
+// ImageIDEquals returns a filter that matches containers created from the image
+// with the given ID (e.g., "sha256:..."). Unlike the image name, the ID changes
+// whenever a tag is updated to point to a different image.
+func ImageIDEquals(id string) Filter {
+	return described(func(c Container) bool {
+		return c.ImageID == id
+	}, "ImageIDEquals", id)
+}
+
+// StopSignalEquals returns a filter that matches containers configured with the
+// given stop signal. An empty signal matches containers using the default.
+func StopSignalEquals(sig string) Filter {
+	return described(func(c Container) bool {
+		return c.StopSignal == sig
+	}, "StopSignalEquals", sig)
+}
+
+// SharesPidNamespaceWith returns a filter that matches containers that share
+// the PID namespace of the given container (i.e., have a PidMode of
+// "container:<containerID>"). Docker records the reference as it was given when
+// the container was created, so this may be an ID or a name.
+func SharesPidNamespaceWith(containerID string) Filter {
+	return described(func(c Container) bool {
+		return c.PidMode == "container:"+containerID
+	}, "SharesPidNamespaceWith", containerID)
+}
+
+// RestartPolicyEquals returns a filter that matches containers with the given
+// restart policy (e.g., "always", "unless-stopped", "on-failure", "no").
+// Containers without a restart policy have an empty policy.
+func RestartPolicyEquals(policy string) Filter {
+	return described(func(c Container) bool {
+		return c.RestartPolicy == policy
+	}, "RestartPolicyEquals", policy)
+}
+
+// WasOOMKilled returns a filter that matches containers that were last killed
+// because they ran out of memory.
+func WasOOMKilled() Filter {
+	return described(func(c Container) bool {
+		return c.OOMKilled
+	}, "WasOOMKilled")
+}
+
+// PublishedOnLoopbackOnly returns a filter that matches containers whose
+// published ports are all bound to a loopback address (127.0.0.0/8 or ::1),
+// and so are not reachable from the network. Ports with an empty host IP are
+// bound to all interfaces, as are "0.0.0.0" and "::". Containers without any
+// published ports also match.
+func PublishedOnLoopbackOnly() Filter {
+	return described(func(c Container) bool {
+		for i := range c.Ports {
+			ip := net.ParseIP(c.Ports[i].HostIP)
+			if ip == nil || !ip.IsLoopback() {
+				return false
+			}
+		}
+		return true
+	}, "PublishedOnLoopbackOnly")
+}
+
+// PublishesPubliclyOn returns a filter that matches containers with at least
+// one port published on the given host IP (e.g. "0.0.0.0" or "192.168.1.10").
+// Addresses are compared by value, so "::" matches "0:0:0:0:0:0:0:0".
+func PublishesPubliclyOn(hostIP string) Filter {
+	want := net.ParseIP(hostIP)
+	return described(func(c Container) bool {
+		for i := range c.Ports {
+			if c.Ports[i].HostIP == hostIP {
+				return true
+			}
+			if ip := net.ParseIP(c.Ports[i].HostIP); ip != nil && ip.Equal(want) {
+				return true
+			}
+		}
+		return false
+	}, "PublishesPubliclyOn", hostIP)
+}
+
+// PubliclyExposed returns a filter that matches containers with at least one
+// port published on all interfaces, i.e. with a host IP of "0.0.0.0", "::",
+// or empty. Ports published on specific addresses aren't considered exposed,
+// even if the address is reachable from other hosts.
+func PubliclyExposed() Filter {
+	return described(func(c Container) bool {
+		for i := range c.Ports {
+			if c.Ports[i].HostIP == "" {
+				return true
+			}
+			if ip := net.ParseIP(c.Ports[i].HostIP); ip != nil && ip.IsUnspecified() {
+				return true
+			}
+		}
+		return false
+	}, "PubliclyExposed")
+}
+
+// ProtocolEquals returns a filter that matches containers with at least one
+// published port using the given protocol (e.g. "tcp", "udp" or "sctp"). The
+// protocol is compared case-insensitively.
+func ProtocolEquals(proto string) Filter {
+	return described(func(c Container) bool {
+		for i := range c.Ports {
+			if strings.EqualFold(c.Ports[i].Protocol, proto) {
+				return true
+			}
+		}
+		return false
+	}, "ProtocolEquals", proto)
+}
+
+// PublishesPortInRange returns a filter that matches containers with at least
+// one port published on a host port between min and max, inclusive. If min is
+// greater than max, the bounds are swapped.
+func PublishesPortInRange(min, max uint16) Filter {
+	if min > max {
+		min, max = max, min
+	}
+
+	return described(func(c Container) bool {
+		for i := range c.Ports {
+			if c.Ports[i].HostPort >= min && c.Ports[i].HostPort <= max {
+				return true
+			}
+		}
+		return false
+	}, "PublishesPortInRange", min, max)
+}
+
+// SwarmService returns a filter that matches swarm tasks belonging to the
+// service with the given name.
+func SwarmService(name string) Filter {
+	return described(func(c Container) bool {
+		return c.Swarm != nil && c.Swarm.ServiceName == name
+	}, "SwarmService", name)
+}
+
+// OnlyInternalNetworks returns a filter that matches containers that are
+// connected to at least one network, and where every network they are connected
+// to is internal (see Network.Internal), so they have no external connectivity.
+func OnlyInternalNetworks() Filter {
+	return described(func(c Container) bool {
+		for i := range c.Networks {
+			if !c.Networks[i].Internal {
+				return false
+			}
+		}
+		return len(c.Networks) > 0
+	}, "OnlyInternalNetworks")
+}
+
+// GatewayEquals returns a filter that matches containers with at least one
+// network using the given gateway (e.g. "172.17.0.1").
+func GatewayEquals(gw string) Filter {
+	return described(func(c Container) bool {
+		for i := range c.Networks {
+			if c.Networks[i].Gateway == gw {
+				return true
+			}
+		}
+		return false
+	}, "GatewayEquals", gw)
+}
+
+// HasIPv4 returns a filter that matches containers with an IPv4 address on at
+// least one network.
+func HasIPv4() Filter {
+	return described(func(c Container) bool {
+		for i := range c.Networks {
+			if c.Networks[i].IPAddress != "" {
+				return true
+			}
+		}
+		return false
+	}, "HasIPv4")
+}
+
+// HasIPv6 returns a filter that matches containers with an IPv6 address on at
+// least one network.
+func HasIPv6() Filter {
+	return described(func(c Container) bool {
+		for i := range c.Networks {
+			if c.Networks[i].IP6Address != "" {
+				return true
+			}
+		}
+		return false
+	}, "HasIPv6")
+}
+
+// ComposeProjectEquals returns a filter that matches containers belonging to the
+// Docker Compose project with the given name.
+func ComposeProjectEquals(name string) Filter {
+	return described(func(c Container) bool {
+		project, ok := c.Labels[composeProjectLabel]
+		return ok && project == name
+	}, "ComposeProjectEquals", name)
+}
+
+// ManagedBy returns a filter that matches containers that appear to be managed
+// by the named orchestrator, based on the labels it applies to containers:
+//
+//   - "compose": has the com.docker.compose.project label
+//   - "swarm": has the com.docker.swarm.service.id label
+//   - "kubernetes": has any label starting with io.kubernetes.
+//   - "nomad": has any label starting with com.hashicorp.nomad.
+//
+// Other orchestrator names never match.
+func ManagedBy(orchestrator string) Filter {
+	var match func(Container) bool
+	switch orchestrator {
+	case "compose":
+		match = func(c Container) bool {
+			_, ok := c.Labels[composeProjectLabel]
+			return ok
+		}
+	case "swarm":
+		match = func(c Container) bool {
+			_, ok := c.Labels["com.docker.swarm.service.id"]
+			return ok
+		}
+	case "kubernetes":
+		match = func(c Container) bool {
+			return hasLabelWithPrefix(c, "io.kubernetes.")
+		}
+	case "nomad":
+		match = func(c Container) bool {
+			return hasLabelWithPrefix(c, "com.hashicorp.nomad.")
+		}
+	default:
+		match = func(Container) bool {
+			return false
+		}
+	}
+
+	return described(match, "ManagedBy", orchestrator)
+}
+
+// hasLabelWithPrefix determines whether the container has any label whose key starts with prefix.
+func hasLabelWithPrefix(c Container, prefix string) bool {
+	for k := range c.Labels {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
 	}
+	return false
 }
@@ -2,6 +2,8 @@ package containuum
 
 import (
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,11 +27,144 @@ var (
 		Labels: map[string]string{"app": "db", "env": "staging"},
 	}
 
+	ipv4Only = Container{
+		ID: "ipv4",
+		Networks: []Network{
+			{Name: "frontend", IPAddress: "172.18.0.2"},
+			{Name: "backend", IPAddress: "172.19.0.2"},
+		},
+	}
+
+	ipv6Only = Container{
+		ID: "ipv6",
+		Networks: []Network{
+			{Name: "frontend", IP6Address: "fd00::2"},
+			{Name: "backend"},
+		},
+	}
+
+	// Each network is single-stack, but the container has both address families.
+	dualStack = Container{
+		ID: "dual",
+		Networks: []Network{
+			{Name: "frontend", IPAddress: "172.18.0.2"},
+			{Name: "backend", IP6Address: "fd00::2"},
+		},
+	}
+
+	multipleGateways = Container{
+		ID: "gateways",
+		Networks: []Network{
+			{Name: "frontend", IPAddress: "172.18.0.2", Gateway: "172.18.0.1"},
+			{Name: "backend", IPAddress: "172.19.0.2", Gateway: "172.19.0.1"},
+			{Name: "db", IPAddress: "172.20.0.2", Internal: true},
+		},
+	}
+
 	runningNoLabels = Container{
 		ID:     "4",
 		State:  "running",
 		Labels: map[string]string{},
 	}
+
+	runningSigquit = Container{
+		ID:         "5",
+		State:      "running",
+		StopSignal: "SIGQUIT",
+	}
+
+	runningHealthy = Container{
+		ID:     "16",
+		State:  "running",
+		Health: "healthy",
+	}
+
+	runningUnhealthy = Container{
+		ID:     "17",
+		State:  "running",
+		Health: "unhealthy",
+	}
+
+	runningStarting = Container{
+		ID:     "18",
+		State:  "running",
+		Health: "starting",
+	}
+
+	exitedHealthy = Container{
+		ID:     "19",
+		State:  "exited",
+		Health: "healthy",
+	}
+
+	runningSidecar = Container{
+		ID:      "6",
+		State:   "running",
+		PidMode: "container:1",
+	}
+
+	runningHostPid = Container{
+		ID:      "7",
+		State:   "running",
+		PidMode: "host",
+	}
+
+	runningAlwaysRestart = Container{
+		ID:            "8",
+		State:         "running",
+		RestartPolicy: "always",
+	}
+
+	composeContainer = Container{
+		ID:     "9",
+		State:  "running",
+		Labels: map[string]string{"com.docker.compose.project": "myapp", "com.docker.compose.service": "web"},
+	}
+
+	swarmContainer = Container{
+		ID:     "10",
+		State:  "running",
+		Labels: map[string]string{"com.docker.swarm.service.id": "abc123", "com.docker.swarm.task.id": "def456"},
+	}
+
+	kubernetesContainer = Container{
+		ID:     "11",
+		State:  "running",
+		Labels: map[string]string{"io.kubernetes.pod.name": "web-1"},
+	}
+
+	nomadContainer = Container{
+		ID:     "12",
+		State:  "running",
+		Labels: map[string]string{"com.hashicorp.nomad.alloc_id": "xyz"},
+	}
+
+	loopbackPorts = Container{
+		ID:    "13",
+		State: "running",
+		Ports: []Port{
+			{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{HostIP: "127.0.1.1", HostPort: 8081, ContainerPort: 81, Protocol: "tcp"},
+			{HostIP: "::1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+	}
+
+	mixedPorts = Container{
+		ID:    "14",
+		State: "running",
+		Ports: []Port{
+			{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{HostIP: "192.168.1.10", HostPort: 8443, ContainerPort: 443, Protocol: "tcp"},
+		},
+	}
+
+	allInterfacesPorts = Container{
+		ID:    "15",
+		State: "running",
+		Ports: []Port{
+			{HostIP: "", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+	}
 )
 
 func TestFilters(t *testing.T) {
@@ -211,6 +346,86 @@ func TestFilters(t *testing.T) {
 			want:      false,
 		},
 
+		// LabelValueIn() tests
+		{
+			name:      "LabelValueIn() matches one of the values",
+			filter:    LabelValueIn("env", "staging", "prod"),
+			container: runningProdWeb,
+			want:      true,
+		},
+		{
+			name:      "LabelValueIn() doesn't match other values",
+			filter:    LabelValueIn("env", "staging", "dev"),
+			container: runningProdWeb,
+			want:      false,
+		},
+		{
+			name:      "LabelValueIn() doesn't match missing label",
+			filter:    LabelValueIn("missing", "prod", ""),
+			container: runningProdWeb,
+			want:      false,
+		},
+		{
+			name:      "LabelValueIn() with no values matches nothing",
+			filter:    LabelValueIn("env"),
+			container: runningProdWeb,
+			want:      false,
+		},
+
+		// LabelPrefixExists() tests
+		{
+			name:      "LabelPrefixExists() matches one of several prefixed labels",
+			filter:    LabelPrefixExists("com.docker.compose."),
+			container: composeContainer,
+			want:      true,
+		},
+		{
+			name:      "LabelPrefixExists() doesn't match other labels",
+			filter:    LabelPrefixExists("traefik."),
+			container: composeContainer,
+			want:      false,
+		},
+		{
+			name:      "LabelPrefixExists() doesn't match container without labels",
+			filter:    LabelPrefixExists("traefik."),
+			container: runningNoLabels,
+			want:      false,
+		},
+
+		// CreatedBefore() tests
+		{
+			name:      "CreatedBefore() matches older container",
+			filter:    CreatedBefore(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)),
+			container: Container{ID: "created1", Created: time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)},
+			want:      true,
+		},
+		{
+			name:      "CreatedBefore() doesn't match newer container",
+			filter:    CreatedBefore(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)),
+			container: Container{ID: "created2", Created: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+			want:      false,
+		},
+		{
+			name:      "CreatedBefore() doesn't match unknown creation time",
+			filter:    CreatedBefore(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)),
+			container: Container{ID: "created3"},
+			want:      false,
+		},
+
+		// ImageIDEquals() tests
+		{
+			name:      "ImageIDEquals() matches",
+			filter:    ImageIDEquals("sha256:aaa"),
+			container: Container{ID: "19", Image: "nginx:latest", ImageID: "sha256:aaa"},
+			want:      true,
+		},
+		{
+			name:      "ImageIDEquals() doesn't match different ID with same tag",
+			filter:    ImageIDEquals("sha256:aaa"),
+			container: Container{ID: "20", Image: "nginx:latest", ImageID: "sha256:bbb"},
+			want:      false,
+		},
+
 		// StateEquals() tests
 		{
 			name:      "StateEquals() matches",
@@ -224,6 +439,538 @@ func TestFilters(t *testing.T) {
 			container: runningProdWeb,
 			want:      false,
 		},
+		{
+			name:      "StateEquals() matches constant",
+			filter:    StateEquals(StateRunning),
+			container: runningProdWeb,
+			want:      true,
+		},
+
+		// StateIn() tests
+		{
+			name:      "StateIn() matches one of the states",
+			filter:    StateIn(StateExited, StateRunning),
+			container: runningProdWeb,
+			want:      true,
+		},
+		{
+			name:      "StateIn() doesn't match other states",
+			filter:    StateIn(StateExited, StateDead),
+			container: runningProdWeb,
+			want:      false,
+		},
+		{
+			name:      "StateIn() with no states matches nothing",
+			filter:    StateIn(),
+			container: runningProdWeb,
+			want:      false,
+		},
+
+		// Healthy() tests
+		{
+			name:      "Healthy() matches container without health check",
+			filter:    Healthy(),
+			container: runningSigquit,
+			want:      true,
+		},
+		{
+			name:      "Healthy() matches healthy container",
+			filter:    Healthy(),
+			container: runningHealthy,
+			want:      true,
+		},
+		{
+			name:      "Healthy() doesn't match unhealthy container",
+			filter:    Healthy(),
+			container: runningUnhealthy,
+			want:      false,
+		},
+		{
+			name:      "Healthy() doesn't match starting container",
+			filter:    Healthy(),
+			container: runningStarting,
+			want:      false,
+		},
+		{
+			name:      "Healthy() doesn't match stopped container",
+			filter:    Healthy(),
+			container: exitedHealthy,
+			want:      false,
+		},
+
+		// StrictlyHealthy() tests
+		{
+			name:      "StrictlyHealthy() doesn't match container without health check",
+			filter:    StrictlyHealthy(),
+			container: runningSigquit,
+			want:      false,
+		},
+		{
+			name:      "StrictlyHealthy() matches healthy container",
+			filter:    StrictlyHealthy(),
+			container: runningHealthy,
+			want:      true,
+		},
+		{
+			name:      "StrictlyHealthy() doesn't match unhealthy container",
+			filter:    StrictlyHealthy(),
+			container: runningUnhealthy,
+			want:      false,
+		},
+		{
+			name:      "StrictlyHealthy() doesn't match starting container",
+			filter:    StrictlyHealthy(),
+			container: runningStarting,
+			want:      false,
+		},
+		{
+			name:      "StrictlyHealthy() doesn't match stopped container",
+			filter:    StrictlyHealthy(),
+			container: exitedHealthy,
+			want:      false,
+		},
+
+		// StopSignalEquals() tests
+		{
+			name:      "StopSignalEquals() matches",
+			filter:    StopSignalEquals("SIGQUIT"),
+			container: runningSigquit,
+			want:      true,
+		},
+		{
+			name:      "StopSignalEquals() doesn't match",
+			filter:    StopSignalEquals("SIGTERM"),
+			container: runningSigquit,
+			want:      false,
+		},
+		{
+			name:      "StopSignalEquals() empty matches default",
+			filter:    StopSignalEquals(""),
+			container: runningProdWeb,
+			want:      true,
+		},
+
+		// SharesPidNamespaceWith() tests
+		{
+			name:      "SharesPidNamespaceWith() matches sidecar",
+			filter:    SharesPidNamespaceWith("1"),
+			container: runningSidecar,
+			want:      true,
+		},
+		{
+			name:      "SharesPidNamespaceWith() different container",
+			filter:    SharesPidNamespaceWith("2"),
+			container: runningSidecar,
+			want:      false,
+		},
+		{
+			name:      "SharesPidNamespaceWith() host mode",
+			filter:    SharesPidNamespaceWith("1"),
+			container: runningHostPid,
+			want:      false,
+		},
+		{
+			name:      "SharesPidNamespaceWith() default mode",
+			filter:    SharesPidNamespaceWith("1"),
+			container: runningProdWeb,
+			want:      false,
+		},
+
+		// RestartPolicyEquals() tests
+		{
+			name:      "RestartPolicyEquals() matches",
+			filter:    RestartPolicyEquals("always"),
+			container: runningAlwaysRestart,
+			want:      true,
+		},
+		{
+			name:      "RestartPolicyEquals() doesn't match",
+			filter:    RestartPolicyEquals("unless-stopped"),
+			container: runningAlwaysRestart,
+			want:      false,
+		},
+		{
+			name:      "RestartPolicyEquals() empty matches no policy",
+			filter:    RestartPolicyEquals(""),
+			container: runningProdWeb,
+			want:      true,
+		},
+
+		// ComposeProjectEquals() tests
+		{
+			name:      "ComposeProjectEquals() matches project",
+			filter:    ComposeProjectEquals("myapp"),
+			container: composeContainer,
+			want:      true,
+		},
+		{
+			name:      "ComposeProjectEquals() doesn't match other project",
+			filter:    ComposeProjectEquals("other"),
+			container: composeContainer,
+			want:      false,
+		},
+		{
+			name:      "ComposeProjectEquals() doesn't match non-compose container",
+			filter:    ComposeProjectEquals(""),
+			container: swarmContainer,
+			want:      false,
+		},
+
+		// ManagedBy() tests
+		{
+			name:      "ManagedBy(compose) matches compose container",
+			filter:    ManagedBy("compose"),
+			container: composeContainer,
+			want:      true,
+		},
+		{
+			name:      "ManagedBy(swarm) doesn't match compose container",
+			filter:    ManagedBy("swarm"),
+			container: composeContainer,
+			want:      false,
+		},
+		{
+			name:      "ManagedBy(swarm) matches swarm container",
+			filter:    ManagedBy("swarm"),
+			container: swarmContainer,
+			want:      true,
+		},
+		{
+			name:      "ManagedBy(kubernetes) matches kubernetes container",
+			filter:    ManagedBy("kubernetes"),
+			container: kubernetesContainer,
+			want:      true,
+		},
+		{
+			name:      "ManagedBy(nomad) matches nomad container",
+			filter:    ManagedBy("nomad"),
+			container: nomadContainer,
+			want:      true,
+		},
+		{
+			name:      "ManagedBy(compose) doesn't match unmanaged container",
+			filter:    ManagedBy("compose"),
+			container: runningProdWeb,
+			want:      false,
+		},
+		{
+			name:      "ManagedBy(swarm) doesn't match unmanaged container",
+			filter:    ManagedBy("swarm"),
+			container: runningNoLabels,
+			want:      false,
+		},
+		{
+			name:      "ManagedBy() unknown orchestrator never matches",
+			filter:    ManagedBy("mesos"),
+			container: composeContainer,
+			want:      false,
+		},
+
+		// WasOOMKilled() tests
+		{
+			name:      "WasOOMKilled() matches OOM killed container",
+			filter:    WasOOMKilled(),
+			container: Container{ID: "16", State: "exited", OOMKilled: true},
+			want:      true,
+		},
+		{
+			name:      "WasOOMKilled() doesn't match other containers",
+			filter:    WasOOMKilled(),
+			container: exitedDevAPI,
+			want:      false,
+		},
+
+		// SwarmService() tests
+		{
+			name:      "SwarmService() matches task of service",
+			filter:    SwarmService("web"),
+			container: Container{ID: "17", State: "running", Swarm: &SwarmInfo{ServiceName: "web", TaskSlot: 1}},
+			want:      true,
+		},
+		{
+			name:      "SwarmService() doesn't match task of other service",
+			filter:    SwarmService("web"),
+			container: Container{ID: "18", State: "running", Swarm: &SwarmInfo{ServiceName: "api", TaskSlot: 1}},
+			want:      false,
+		},
+		{
+			name:      "SwarmService() doesn't match non-swarm container",
+			filter:    SwarmService("web"),
+			container: runningProdWeb,
+			want:      false,
+		},
+
+		// PublishesPubliclyOn() tests
+		{
+			name:      "PublishesPubliclyOn() matches host IP",
+			filter:    PublishesPubliclyOn("192.168.1.10"),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPubliclyOn() doesn't match other host IPs",
+			filter:    PublishesPubliclyOn("0.0.0.0"),
+			container: loopbackPorts,
+			want:      false,
+		},
+		{
+			name:      "PublishesPubliclyOn() compares IPv6 addresses by value",
+			filter:    PublishesPubliclyOn("0:0:0:0:0:0:0:1"),
+			container: loopbackPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPubliclyOn() doesn't match no published ports",
+			filter:    PublishesPubliclyOn("0.0.0.0"),
+			container: runningNoLabels,
+			want:      false,
+		},
+
+		// PubliclyExposed() tests
+		{
+			name:      "PubliclyExposed() doesn't match localhost-only ports",
+			filter:    PubliclyExposed(),
+			container: loopbackPorts,
+			want:      false,
+		},
+		{
+			name:      "PubliclyExposed() doesn't match specific addresses",
+			filter:    PubliclyExposed(),
+			container: mixedPorts,
+			want:      false,
+		},
+		{
+			name:      "PubliclyExposed() matches empty host IP",
+			filter:    PubliclyExposed(),
+			container: allInterfacesPorts,
+			want:      true,
+		},
+		{
+			name:      "PubliclyExposed() matches IPv4 any-address alongside localhost",
+			filter:    PubliclyExposed(),
+			container: Container{Ports: []Port{{HostIP: "127.0.0.1", HostPort: 8080}, {HostIP: "0.0.0.0", HostPort: 8443}}},
+			want:      true,
+		},
+		{
+			name:      "PubliclyExposed() matches IPv6 any-address",
+			filter:    PubliclyExposed(),
+			container: Container{Ports: []Port{{HostIP: "::", HostPort: 8080}}},
+			want:      true,
+		},
+		{
+			name:      "PubliclyExposed() doesn't match no published ports",
+			filter:    PubliclyExposed(),
+			container: runningNoLabels,
+			want:      false,
+		},
+
+		// GatewayEquals() tests
+		{
+			name:      "GatewayEquals() matches first network's gateway",
+			filter:    GatewayEquals("172.18.0.1"),
+			container: multipleGateways,
+			want:      true,
+		},
+		{
+			name:      "GatewayEquals() matches later network's gateway",
+			filter:    GatewayEquals("172.19.0.1"),
+			container: multipleGateways,
+			want:      true,
+		},
+		{
+			name:      "GatewayEquals() doesn't match other gateways",
+			filter:    GatewayEquals("172.20.0.1"),
+			container: multipleGateways,
+			want:      false,
+		},
+		{
+			name:      "GatewayEquals() doesn't match container without networks",
+			filter:    GatewayEquals("172.18.0.1"),
+			container: runningNoLabels,
+			want:      false,
+		},
+
+		// OnlyInternalNetworks() tests
+		{
+			name:      "OnlyInternalNetworks() matches container on internal networks",
+			filter:    OnlyInternalNetworks(),
+			container: Container{ID: "21", Networks: []Network{{Name: "backend", Internal: true}, {Name: "db", Internal: true}}},
+			want:      true,
+		},
+		{
+			name:      "OnlyInternalNetworks() doesn't match container also on external network",
+			filter:    OnlyInternalNetworks(),
+			container: Container{ID: "22", Networks: []Network{{Name: "backend", Internal: true}, {Name: "frontend"}}},
+			want:      false,
+		},
+		{
+			name:      "OnlyInternalNetworks() doesn't match container without networks",
+			filter:    OnlyInternalNetworks(),
+			container: runningNoLabels,
+			want:      false,
+		},
+
+		// HasIPv4() and HasIPv6() tests
+		{
+			name:      "HasIPv4() matches IPv4-only container",
+			filter:    HasIPv4(),
+			container: ipv4Only,
+			want:      true,
+		},
+		{
+			name:      "HasIPv4() doesn't match IPv6-only container",
+			filter:    HasIPv4(),
+			container: ipv6Only,
+			want:      false,
+		},
+		{
+			name:      "HasIPv4() matches dual-stack container",
+			filter:    HasIPv4(),
+			container: dualStack,
+			want:      true,
+		},
+		{
+			name:      "HasIPv4() doesn't match container without networks",
+			filter:    HasIPv4(),
+			container: runningNoLabels,
+			want:      false,
+		},
+		{
+			name:      "HasIPv6() doesn't match IPv4-only container",
+			filter:    HasIPv6(),
+			container: ipv4Only,
+			want:      false,
+		},
+		{
+			name:      "HasIPv6() matches IPv6-only container",
+			filter:    HasIPv6(),
+			container: ipv6Only,
+			want:      true,
+		},
+		{
+			name:      "HasIPv6() matches dual-stack container",
+			filter:    HasIPv6(),
+			container: dualStack,
+			want:      true,
+		},
+		{
+			name:      "HasIPv6() doesn't match container without networks",
+			filter:    HasIPv6(),
+			container: runningNoLabels,
+			want:      false,
+		},
+
+		// PublishedOnLoopbackOnly() tests
+		{
+			name:      "PublishedOnLoopbackOnly() matches loopback-only bindings",
+			filter:    PublishedOnLoopbackOnly(),
+			container: loopbackPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishedOnLoopbackOnly() doesn't match mixed bindings",
+			filter:    PublishedOnLoopbackOnly(),
+			container: mixedPorts,
+			want:      false,
+		},
+		{
+			name:      "PublishedOnLoopbackOnly() doesn't match empty host IP",
+			filter:    PublishedOnLoopbackOnly(),
+			container: allInterfacesPorts,
+			want:      false,
+		},
+		{
+			name:      "PublishedOnLoopbackOnly() doesn't match 0.0.0.0",
+			filter:    PublishedOnLoopbackOnly(),
+			container: Container{Ports: []Port{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80}}},
+			want:      false,
+		},
+		{
+			name:      "PublishedOnLoopbackOnly() matches no published ports",
+			filter:    PublishedOnLoopbackOnly(),
+			container: runningNoLabels,
+			want:      true,
+		},
+
+		// ProtocolEquals() tests
+		{
+			name:      "ProtocolEquals() matches port with protocol",
+			filter:    ProtocolEquals("tcp"),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "ProtocolEquals() matches one of several ports",
+			filter:    ProtocolEquals("sctp"),
+			container: Container{Ports: []Port{{HostPort: 53, ContainerPort: 53, Protocol: "udp"}, {HostPort: 9899, ContainerPort: 9899, Protocol: "sctp"}}},
+			want:      true,
+		},
+		{
+			name:      "ProtocolEquals() is case-insensitive",
+			filter:    ProtocolEquals("TCP"),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "ProtocolEquals() doesn't match other protocols",
+			filter:    ProtocolEquals("udp"),
+			container: mixedPorts,
+			want:      false,
+		},
+		{
+			name:      "ProtocolEquals() doesn't match no published ports",
+			filter:    ProtocolEquals("tcp"),
+			container: runningNoLabels,
+			want:      false,
+		},
+		{
+			name:      "PublishesPortInRange() matches port in range",
+			filter:    PublishesPortInRange(8000, 8100),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPortInRange() matches lower bound",
+			filter:    PublishesPortInRange(8443, 9000),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPortInRange() matches upper bound",
+			filter:    PublishesPortInRange(8081, 8443),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPortInRange() matches single port range",
+			filter:    PublishesPortInRange(8080, 8080),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPortInRange() doesn't match ports outside range",
+			filter:    PublishesPortInRange(8081, 8442),
+			container: mixedPorts,
+			want:      false,
+		},
+		{
+			name:      "PublishesPortInRange() swaps reversed bounds",
+			filter:    PublishesPortInRange(8100, 8000),
+			container: mixedPorts,
+			want:      true,
+		},
+		{
+			name:      "PublishesPortInRange() doesn't match outside reversed bounds",
+			filter:    PublishesPortInRange(8442, 8081),
+			container: mixedPorts,
+			want:      false,
+		},
+		{
+			name:      "PublishesPortInRange() doesn't match no published ports",
+			filter:    PublishesPortInRange(0, 65535),
+			container: runningNoLabels,
+			want:      false,
+		},
 
 		// Nested filters
 		{
@@ -274,3 +1021,277 @@ func TestFilters(t *testing.T) {
 		})
 	}
 }
+
+func TestOlderThan(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		filter := All(StateEquals("exited"), OlderThan(24*time.Hour))
+		c := Container{ID: "1", State: "exited", Created: time.Now().Add(-23 * time.Hour)}
+
+		assert.False(t, filter(c), "should not match a container younger than the duration")
+
+		time.Sleep(2 * time.Hour)
+		assert.True(t, filter(c), "should match once the container is older than the duration")
+
+		assert.False(t, OlderThan(time.Hour)(Container{ID: "2", State: "exited"}), "should not match unknown creation time")
+	})
+}
+
+func TestMemoize(t *testing.T) {
+	t.Run("invokes filter once per distinct container", func(t *testing.T) {
+		calls := map[string]int{}
+		counting := func(c Container) bool {
+			calls[c.ID]++
+			return c.State == "running"
+		}
+
+		memoized := Memoize(counting)
+		filter := All(memoized, Any(memoized, LabelExists("app")), Not(Not(memoized)))
+
+		scope := &filterScope{}
+		assert.True(t, scope.match(filter, runningProdWeb))
+		assert.False(t, scope.match(filter, exitedDevAPI))
+		assert.True(t, scope.match(filter, runningProdWeb))
+
+		assert.Equal(t, map[string]int{"1": 1, "2": 1}, calls)
+	})
+
+	t.Run("cache does not persist across scopes", func(t *testing.T) {
+		calls := 0
+		memoized := Memoize(func(c Container) bool {
+			calls++
+			return true
+		})
+
+		scope := &filterScope{}
+		scope.match(memoized, runningProdWeb)
+		scope.match(memoized, runningProdWeb)
+		assert.Equal(t, 1, calls)
+
+		scope = &filterScope{}
+		scope.match(memoized, runningProdWeb)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("scopes do not share results", func(t *testing.T) {
+		calls := 0
+		memoized := Memoize(func(c Container) bool {
+			calls++
+			return true
+		})
+
+		first, second := &filterScope{}, &filterScope{}
+		first.match(memoized, runningProdWeb)
+		second.match(memoized, runningProdWeb)
+		first.match(memoized, runningProdWeb)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("does not cache outside a scope", func(t *testing.T) {
+		calls := 0
+		memoized := Memoize(func(c Container) bool {
+			calls++
+			return true
+		})
+
+		memoized(runningProdWeb)
+		memoized(runningProdWeb)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("changed container is re-evaluated", func(t *testing.T) {
+		calls := 0
+		memoized := Memoize(func(c Container) bool {
+			calls++
+			return c.State == "running"
+		})
+
+		scope := &filterScope{}
+		changed := runningProdWeb
+		changed.State = "exited"
+
+		assert.True(t, scope.match(memoized, runningProdWeb))
+		assert.False(t, scope.match(memoized, changed))
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr string
+	}{
+		{
+			name: "defaults are valid",
+		},
+		{
+			name:    "valid reconnect options",
+			options: []Option{WithAutoReconnect(time.Second, time.Minute, 0)},
+		},
+		{
+			name:    "negative debounce",
+			options: []Option{WithDebounce(-time.Second)},
+			wantErr: "debounce must not be negative",
+		},
+		{
+			name:    "unknown debounce strategy",
+			options: []Option{WithDebounceStrategy(DebounceStrategy(42))},
+			wantErr: "unknown debounce strategy 42",
+		},
+		{
+			name:    "valid watched actions",
+			options: []Option{WithWatchedActions("start", "die", "health_status")},
+		},
+		{
+			name:    "unsupported watched action",
+			options: []Option{WithWatchedActions("start", "exec_start")},
+			wantErr: `unsupported watched action "exec_start"`,
+		},
+		{
+			name:    "negative reconnect reset time",
+			options: []Option{WithReconnectResetAfter(-time.Second)},
+			wantErr: "reconnect reset time must not be negative",
+		},
+		{
+			name:    "zero reconnect reset time with auto reconnect",
+			options: []Option{WithAutoReconnect(time.Second, time.Second, 3), WithReconnectResetAfter(0)},
+			wantErr: "reconnect reset time must be positive",
+		},
+		{
+			name:    "zero reconnect reset time without auto reconnect",
+			options: []Option{WithReconnectResetAfter(0)},
+		},
+		{
+			name:    "negative per-container debounce",
+			options: []Option{WithPerContainerDebounce(-time.Second)},
+			wantErr: "per-container debounce must not be negative",
+		},
+		{
+			name:    "network labels with summary only",
+			options: []Option{WithSummaryOnly(), WithNetworkLabels()},
+			wantErr: "network labels can't be used with summary only mode",
+		},
+		{
+			name:    "negative max containers",
+			options: []Option{WithMaxContainers(-1, ContainerLimitError)},
+			wantErr: "max containers must not be negative",
+		},
+		{
+			name:    "unknown container limit policy",
+			options: []Option{WithMaxContainers(10, ContainerLimitPolicy(99))},
+			wantErr: "unknown container limit policy 99",
+		},
+		{
+			name:    "negative event buffer",
+			options: []Option{WithEventBuffer(-1)},
+			wantErr: "event buffer must not be negative",
+		},
+		{
+			name:    "negative callback timeout",
+			options: []Option{WithCallbackTimeout(-time.Second)},
+			wantErr: "callback timeout must not be negative",
+		},
+		{
+			name:    "negative health poll interval",
+			options: []Option{WithHealthPollInterval(-time.Second)},
+			wantErr: "health poll interval must not be negative",
+		},
+		{
+			name:    "negative max staleness",
+			options: []Option{WithStaleOnListError(-time.Second)},
+			wantErr: "max staleness must not be negative",
+		},
+		{
+			name:    "negative max debounce time",
+			options: []Option{WithMaxDebounceTime(-time.Second)},
+			wantErr: "max debounce time must not be negative",
+		},
+		{
+			name:    "negative max idle time",
+			options: []Option{WithMaxIdleTime(-time.Second)},
+			wantErr: "max idle time must not be negative",
+		},
+		{
+			name:    "zero max idle time",
+			options: []Option{WithMaxIdleTime(0)},
+			wantErr: "max idle time must be positive",
+		},
+		{
+			name:    "negative full resync interval",
+			options: []Option{WithFullResyncInterval(-time.Second)},
+			wantErr: "full resync interval must not be negative",
+		},
+		{
+			name:    "negative event gap threshold",
+			options: []Option{WithEventGapThreshold(-time.Second)},
+			wantErr: "event gap threshold must not be negative",
+		},
+		{
+			name:    "negative startup timeout",
+			options: []Option{WithStartupTimeout(-time.Second)},
+			wantErr: "startup timeout must not be negative",
+		},
+		{
+			name:    "negative min callback interval",
+			options: []Option{WithMinCallbackInterval(-time.Second)},
+			wantErr: "min callback interval must not be negative",
+		},
+		{
+			name:    "negative connect timeout",
+			options: []Option{WithConnectTimeout(-time.Second)},
+			wantErr: "connect timeout must not be negative",
+		},
+		{
+			name:    "negative reconnect stable time",
+			options: []Option{WithReconnectStableTime(-time.Second)},
+			wantErr: "reconnect stable time must not be negative",
+		},
+		{
+			name:    "max debounce time less than debounce",
+			options: []Option{WithDebounce(time.Second), WithMaxDebounceTime(500 * time.Millisecond)},
+			wantErr: "max debounce time (500ms) must not be less than debounce (1s)",
+		},
+		{
+			name:    "negative inspect retries",
+			options: []Option{WithInspectRetries(-1)},
+			wantErr: "inspect retries must not be negative",
+		},
+		{
+			name:    "negative event log sampling",
+			options: []Option{WithEventLogSampling(-1)},
+			wantErr: "event log sampling must not be negative",
+		},
+		{
+			name:    "zero min reconnect delay",
+			options: []Option{WithAutoReconnect(0, time.Minute, 0)},
+			wantErr: "min reconnect delay must be positive",
+		},
+		{
+			name:    "min reconnect delay greater than max",
+			options: []Option{WithAutoReconnect(time.Minute, time.Second, 0)},
+			wantErr: "max reconnect delay (1s) must not be less than min reconnect delay (1m0s)",
+		},
+		{
+			name:    "negative max reconnect retries",
+			options: []Option{WithAutoReconnect(time.Second, time.Minute, -1)},
+			wantErr: "max reconnect retries must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			for _, opt := range tt.options {
+				opt(cfg)
+			}
+
+			err := cfg.validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidOption)
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package otelcontainuum records the work done by containuum monitors as
+// OpenTelemetry spans. It is separate so that containuum itself doesn't depend
+// on OpenTelemetry.
+package otelcontainuum
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/csmith/containuum"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer returns an option that records spans using the given
+// OpenTelemetry tracer. See containuum.WithTracer for the spans recorded.
+func WithTracer(tracer trace.Tracer) containuum.Option {
+	return containuum.WithTracer(Tracer(tracer))
+}
+
+// Tracer adapts an OpenTelemetry tracer to a containuum.Tracer.
+func Tracer(tracer trace.Tracer) containuum.Tracer {
+	return otelTracer{tracer: tracer}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+func (t otelTracer) Start(ctx context.Context, name string, attrs ...containuum.Attribute) (context.Context, containuum.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+	return ctx, otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attrs ...containuum.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s otelSpan) Fail(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+// convert converts attributes to their OpenTelemetry equivalents.
+func convert(attrs []containuum.Attribute) []attribute.KeyValue {
+	res := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case int:
+			res[i] = attribute.Int(attr.Key, v)
+		case string:
+			res[i] = attribute.String(attr.Key, v)
+		default:
+			res[i] = attribute.String(attr.Key, fmt.Sprint(v))
+		}
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package otelcontainuum
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/csmith/containuum"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := Tracer(provider.Tracer("test"))
+
+	ctx, parent := tracer.Start(context.Background(), "parent", containuum.Attribute{Key: "name", Value: "main"})
+	parent.SetAttributes(containuum.Attribute{Key: "count", Value: 3})
+
+	_, child := tracer.Start(ctx, "child")
+	child.Fail(errors.New("broken"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "broken", spans[0].Status().Description)
+	if assert.Len(t, spans[0].Events(), 1) {
+		assert.Equal(t, "exception", spans[0].Events()[0].Name)
+	}
+
+	assert.Equal(t, "parent", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), attribute.String("name", "main"))
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("count", 3))
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
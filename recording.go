@@ -0,0 +1,349 @@
+package containuum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+)
+
+// Types of recorded call.
+const (
+	recordedEvent       = "event"
+	recordedEventError  = "eventError"
+	recordedEventClosed = "eventClosed"
+	recordedList        = "list"
+	recordedInspect     = "inspect"
+	recordedNetwork     = "networkInspect"
+)
+
+// recordedCall is a single entry in a recording, written as one line of JSON.
+type recordedCall struct {
+	Type       string                     `json:"type"`
+	Event      *events.Message            `json:"event,omitempty"`
+	Containers []container.Summary        `json:"containers,omitempty"`
+	ID         string                     `json:"id,omitempty"`
+	Inspect    *container.InspectResponse `json:"inspect,omitempty"`
+	Network    *network.Inspect           `json:"network,omitempty"`
+	Error      string                     `json:"error,omitempty"`
+	NotFound   bool                       `json:"notFound,omitempty"`
+}
+
+// recordingClient is a DockerClient that records the results of calls to an
+// inner client.
+type recordingClient struct {
+	inner DockerClient
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewRecordingClient wraps a DockerClient, writing the events, container lists
+// and inspections it returns to w, as one JSON object per line. The recording
+// can be replayed with NewReplayClient, e.g. to reproduce a bug or write a
+// deterministic test based on real traffic.
+//
+// The returned client implements NetworkInspector, but network inspections fail
+// unless the inner client also implements it.
+//
+// Errors writing to w are logged, but don't affect the calls being recorded.
+func NewRecordingClient(inner DockerClient, w io.Writer) DockerClient {
+	return &recordingClient{
+		inner:   inner,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// record writes the given call to the recording.
+func (r *recordingClient) record(call recordedCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.encoder.Encode(call); err != nil {
+		Log("Failed to record docker call", "type", call.Type, "error", err)
+	}
+}
+
+func (r *recordingClient) Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
+	innerMsgs, innerErrs := r.inner.Events(ctx, options)
+	msgs := make(chan events.Message)
+	errs := make(chan error, 1)
+
+	go func() {
+		for {
+			select {
+			case msg, ok := <-innerMsgs:
+				if !ok {
+					r.record(recordedCall{Type: recordedEventClosed})
+					close(msgs)
+					return
+				}
+				r.record(recordedCall{Type: recordedEvent, Event: &msg})
+				select {
+				case msgs <- msg:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-innerErrs:
+				if !ok {
+					innerErrs = nil
+					continue
+				}
+				if ctx.Err() == nil {
+					r.record(recordedCall{Type: recordedEventError, Error: errorString(err)})
+				}
+				errs <- err
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return msgs, errs
+}
+
+func (r *recordingClient) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	containers, err := r.inner.ContainerList(ctx, options)
+	r.record(recordedCall{Type: recordedList, Containers: containers, Error: errorString(err)})
+	return containers, err
+}
+
+func (r *recordingClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	inspect, err := r.inner.ContainerInspect(ctx, containerID)
+	call := recordedCall{Type: recordedInspect, ID: containerID, Error: errorString(err), NotFound: cerrdefs.IsNotFound(err)}
+	if err == nil {
+		call.Inspect = &inspect
+	}
+	r.record(call)
+	return inspect, err
+}
+
+// NetworkInspect records the result of inspecting a network, if the inner
+// client implements NetworkInspector. Otherwise it returns an error.
+func (r *recordingClient) NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+	inspector, ok := r.inner.(NetworkInspector)
+	if !ok {
+		return network.Inspect{}, fmt.Errorf("inner client can't inspect networks: %w", cerrdefs.ErrNotImplemented)
+	}
+
+	inspect, err := inspector.NetworkInspect(ctx, networkID, options)
+	call := recordedCall{Type: recordedNetwork, ID: networkID, Error: errorString(err), NotFound: cerrdefs.IsNotFound(err)}
+	if err == nil {
+		call.Network = &inspect
+	}
+	r.record(call)
+	return inspect, err
+}
+
+// errorString returns the message of the given error, or "" if it is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// replayedEvent is an entry from the event stream of a recording, along with
+// the number of calls that were recorded before it.
+type replayedEvent struct {
+	call     recordedCall
+	lists    int
+	inspects int
+}
+
+// replayClient is a DockerClient that replays a recording.
+type replayClient struct {
+	mu       sync.Mutex
+	stream   []replayedEvent
+	lists    []recordedCall
+	inspects map[string][]recordedCall
+	networks map[string][]recordedCall
+
+	listCalls    int
+	inspectCalls map[string]int
+	networkCalls map[string]int
+	totalInspect int
+	called       chan struct{}
+}
+
+// NewReplayClient creates a DockerClient that replays a recording made by
+// NewRecordingClient.
+//
+// Container lists are returned in the order they were recorded, as are the
+// inspections of each container and network. Once they have been exhausted,
+// the last result is repeated; containers and networks that were never
+// inspected are reported as not found. Each event is only sent once the calls that were recorded before it
+// have been replayed, so that the events and results are interleaved in the
+// same way as when they were recorded. After the recorded events have been
+// sent, the event stream stays open until the context is cancelled.
+func NewReplayClient(r io.Reader) (DockerClient, error) {
+	client := &replayClient{
+		inspects:     make(map[string][]recordedCall),
+		inspectCalls: make(map[string]int),
+		networks:     make(map[string][]recordedCall),
+		networkCalls: make(map[string]int),
+		called:       make(chan struct{}),
+	}
+
+	decoder := json.NewDecoder(r)
+	var inspects int
+	for {
+		var call recordedCall
+		if err := decoder.Decode(&call); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse recording: %w", err)
+		}
+
+		switch call.Type {
+		case recordedEvent, recordedEventError, recordedEventClosed:
+			client.stream = append(client.stream, replayedEvent{call: call, lists: len(client.lists), inspects: inspects})
+		case recordedList:
+			client.lists = append(client.lists, call)
+		case recordedInspect:
+			client.inspects[call.ID] = append(client.inspects[call.ID], call)
+			inspects++
+		case recordedNetwork:
+			client.networks[call.ID] = append(client.networks[call.ID], call)
+		default:
+			return nil, fmt.Errorf("failed to parse recording: unknown call type %q", call.Type)
+		}
+	}
+
+	return client, nil
+}
+
+func (r *replayClient) Events(ctx context.Context, _ events.ListOptions) (<-chan events.Message, <-chan error) {
+	msgs := make(chan events.Message)
+	errs := make(chan error, 1)
+
+	go func() {
+		for {
+			event, ok := r.nextEvent(ctx)
+			if !ok {
+				<-ctx.Done()
+				return
+			}
+
+			switch event.Type {
+			case recordedEvent:
+				select {
+				case msgs <- *event.Event:
+				case <-ctx.Done():
+					return
+				}
+			case recordedEventError:
+				errs <- errors.New(event.Error)
+				return
+			case recordedEventClosed:
+				close(msgs)
+				return
+			}
+		}
+	}()
+
+	return msgs, errs
+}
+
+// nextEvent waits until the calls recorded before the next entry in the event
+// stream have been replayed, then returns it. Returns false if there are no
+// more entries, or the context is cancelled.
+func (r *replayClient) nextEvent(ctx context.Context) (recordedCall, bool) {
+	for {
+		r.mu.Lock()
+		if len(r.stream) == 0 {
+			r.mu.Unlock()
+			return recordedCall{}, false
+		}
+		next := r.stream[0]
+		if r.listCalls >= next.lists && r.totalInspect >= next.inspects {
+			r.stream = r.stream[1:]
+			r.mu.Unlock()
+			return next.call, true
+		}
+		called := r.called
+		r.mu.Unlock()
+
+		select {
+		case <-called:
+		case <-ctx.Done():
+			return recordedCall{}, false
+		}
+	}
+}
+
+// replayed records that a call has been replayed, waking any pending events.
+// Must be called with the lock held.
+func (r *replayClient) replayed() {
+	close(r.called)
+	r.called = make(chan struct{})
+}
+
+func (r *replayClient) ContainerList(_ context.Context, _ container.ListOptions) ([]container.Summary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.replayed()
+
+	if len(r.lists) == 0 {
+		r.listCalls++
+		return nil, nil
+	}
+
+	call := r.lists[min(r.listCalls, len(r.lists)-1)]
+	r.listCalls++
+	if call.Error != "" {
+		return nil, errors.New(call.Error)
+	}
+	return call.Containers, nil
+}
+
+func (r *replayClient) ContainerInspect(_ context.Context, containerID string) (container.InspectResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.replayed()
+
+	r.totalInspect++
+	calls := r.inspects[containerID]
+	if len(calls) == 0 {
+		return container.InspectResponse{}, fmt.Errorf("container not found: %s: %w", containerID, cerrdefs.ErrNotFound)
+	}
+
+	call := calls[min(r.inspectCalls[containerID], len(calls)-1)]
+	r.inspectCalls[containerID]++
+	switch {
+	case call.NotFound:
+		return container.InspectResponse{}, fmt.Errorf("%s: %w", call.Error, cerrdefs.ErrNotFound)
+	case call.Error != "":
+		return container.InspectResponse{}, errors.New(call.Error)
+	default:
+		return *call.Inspect, nil
+	}
+}
+
+func (r *replayClient) NetworkInspect(_ context.Context, networkID string, _ network.InspectOptions) (network.Inspect, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := r.networks[networkID]
+	if len(calls) == 0 {
+		return network.Inspect{}, fmt.Errorf("network not found: %s: %w", networkID, cerrdefs.ErrNotFound)
+	}
+
+	call := calls[min(r.networkCalls[networkID], len(calls)-1)]
+	r.networkCalls[networkID]++
+	switch {
+	case call.NotFound:
+		return network.Inspect{}, fmt.Errorf("%s: %w", call.Error, cerrdefs.ErrNotFound)
+	case call.Error != "":
+		return network.Inspect{}, errors.New(call.Error)
+	default:
+		return *call.Network, nil
+	}
+}
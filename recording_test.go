@@ -0,0 +1,188 @@
+package containuum
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectCallbacks runs a monitor against the given client until the test
+// function returns, returning the containers passed to each callback.
+func collectCallbacks(t *testing.T, client DockerClient, test func()) [][]Container {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls [][]Container
+	mu := sync.Mutex{}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Run(ctx, func(containers []Container) {
+			mu.Lock()
+			calls = append(calls, containers)
+			mu.Unlock()
+		},
+			WithDockerClient(client),
+			WithDebounce(10*time.Millisecond),
+		)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	synctest.Wait()
+
+	test()
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return calls
+}
+
+func TestRecordingClient_RoundTrip(t *testing.T) {
+	var recording bytes.Buffer
+	var recorded [][]Container
+
+	synctest.Test(t, func(t *testing.T) {
+		mock := newMockDockerClient()
+		mock.setContainers(
+			container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{
+					ID:    "container1",
+					Name:  "/test1",
+					State: &container.State{Status: "running"},
+				},
+				Config: &container.Config{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+			},
+		)
+
+		recorded = collectCallbacks(t, NewRecordingClient(mock, &recording), func() {
+			mock.setContainers(
+				container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    "container1",
+						Name:  "/test1",
+						State: &container.State{Status: "exited"},
+					},
+					Config: &container.Config{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+				},
+				container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    "container2",
+						Name:  "/test2",
+						State: &container.State{Status: "running"},
+					},
+					Config: &container.Config{Image: "redis:latest"},
+				},
+			)
+			mock.eventCh <- events.Message{Type: "container", Action: "die", Actor: events.Actor{ID: "container1"}}
+			time.Sleep(50 * time.Millisecond)
+			synctest.Wait()
+
+			mock.setContainers(
+				container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    "container2",
+						Name:  "/test2",
+						State: &container.State{Status: "running"},
+					},
+					Config: &container.Config{Image: "redis:latest"},
+				},
+			)
+			mock.eventCh <- events.Message{Type: "container", Action: "destroy", Actor: events.Actor{ID: "container1"}}
+			time.Sleep(50 * time.Millisecond)
+			synctest.Wait()
+		})
+	})
+
+	require.Len(t, recorded, 3)
+
+	var replayed [][]Container
+	synctest.Test(t, func(t *testing.T) {
+		replay, err := NewReplayClient(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+
+		replayed = collectCallbacks(t, replay, func() {
+			time.Sleep(time.Second)
+			synctest.Wait()
+		})
+	})
+
+	assert.Equal(t, recorded, replayed)
+}
+
+func TestRecordingClient_Errors(t *testing.T) {
+	mock := newMockDockerClient()
+	mock.listErr = errors.New("daemon unavailable")
+	mock.inspectErr["broken"] = errors.New("inspect failed")
+
+	var recording bytes.Buffer
+	recorder := NewRecordingClient(mock, &recording)
+
+	_, err := recorder.ContainerList(context.Background(), container.ListOptions{})
+	assert.EqualError(t, err, "daemon unavailable")
+	_, err = recorder.ContainerInspect(context.Background(), "broken")
+	assert.EqualError(t, err, "inspect failed")
+	_, err = recorder.ContainerInspect(context.Background(), "missing")
+	assert.True(t, cerrdefs.IsNotFound(err))
+
+	replay, err := NewReplayClient(&recording)
+	require.NoError(t, err)
+
+	_, err = replay.ContainerList(context.Background(), container.ListOptions{})
+	assert.EqualError(t, err, "daemon unavailable")
+	_, err = replay.ContainerInspect(context.Background(), "broken")
+	assert.EqualError(t, err, "inspect failed")
+	assert.False(t, cerrdefs.IsNotFound(err))
+	_, err = replay.ContainerInspect(context.Background(), "missing")
+	assert.True(t, cerrdefs.IsNotFound(err), "not found errors should be preserved")
+	_, err = replay.ContainerInspect(context.Background(), "unrecorded")
+	assert.True(t, cerrdefs.IsNotFound(err), "unrecorded containers should not be found")
+}
+
+func TestRecordingClient_EventStreamError(t *testing.T) {
+	var recording bytes.Buffer
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock := newMockDockerClient()
+		recorder := NewRecordingClient(mock, &recording)
+		_, errs := recorder.Events(ctx, events.ListOptions{})
+
+		mock.errCh <- errors.New("stream broken")
+		assert.EqualError(t, <-errs, "stream broken")
+	})
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		replay, err := NewReplayClient(&recording)
+		require.NoError(t, err)
+
+		_, errs := replay.Events(ctx, events.ListOptions{})
+		assert.EqualError(t, <-errs, "stream broken")
+	})
+}
+
+func TestNewReplayClient_InvalidRecording(t *testing.T) {
+	_, err := NewReplayClient(strings.NewReader("not json"))
+	assert.Error(t, err)
+
+	_, err = NewReplayClient(strings.NewReader(`{"type":"unknown"}`))
+	assert.ErrorContains(t, err, "unknown call type")
+}
@@ -0,0 +1,87 @@
+package containuum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// DiffAgainstFile compares the current containers against a snapshot previously
+// saved at path as a JSON array of Container. The current containers are
+// retrieved once using the given options (filters, transforms, and so on are
+// applied as normal), and compared using Diff. If the file doesn't exist, all
+// current containers are considered to have been added.
+func DiffAgainstFile(ctx context.Context, path string, opts ...Option) (added, removed, changed []Container, err error) {
+	previous, err := loadSnapshot(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	current, err := Snapshot(ctx, opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	added, removed, changed = Diff(previous, current)
+	return added, removed, changed, nil
+}
+
+// loadSnapshot reads a JSON array of containers from the given path.
+// Returns no containers if the file does not exist.
+func loadSnapshot(path string) ([]Container, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var containers []Container
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	return containers, nil
+}
+
+// Snapshot retrieves the current set of matching containers once, without
+// subscribing to events or invoking any callbacks. Options are applied as they
+// would be for Run, so the client, filter, transform and sort order can all be
+// configured; options that relate to watching for changes have no effect.
+//
+// This is useful for one-off queries, such as a CLI status command.
+func Snapshot(ctx context.Context, opts ...Option) ([]Container, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	dockerClient, cleanup, err := cfg.dockerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cleanup() }()
+
+	if err := cfg.checkClient(dockerClient); err != nil {
+		return nil, err
+	}
+
+	m := newMonitor(ctx, cfg, dockerClient, nil)
+	containers, err := m.gatherContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve containers: %w", err)
+	}
+
+	if cfg.less != nil {
+		sort.SliceStable(containers, func(i, j int) bool {
+			return cfg.less(containers[i], containers[j])
+		})
+	}
+	return containers, nil
+}
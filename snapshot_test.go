@@ -0,0 +1,160 @@
+package containuum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAgainstFile(t *testing.T) {
+	mock := newMockDockerClient()
+	mock.setContainers(
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "kept",
+				Name:  "/kept",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+		},
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "changed",
+				Name:  "/changed",
+				State: &container.State{Status: "exited"},
+			},
+			Config: &container.Config{Image: "redis:latest"},
+		},
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "added",
+				Name:  "/added",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "postgres:latest"},
+		},
+	)
+
+	saved := []Container{
+		{ID: "kept", Name: "kept", Image: "nginx:latest", State: "running", Labels: map[string]string{"app": "web"}},
+		{ID: "changed", Name: "changed", Image: "redis:latest", State: "running"},
+		{ID: "removed", Name: "removed", Image: "alpine:latest", State: "running"},
+	}
+	data, err := json.Marshal(saved)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	added, removed, changed, err := DiffAgainstFile(context.Background(), path, WithDockerClient(mock))
+	require.NoError(t, err)
+
+	if assert.Len(t, added, 1) {
+		assert.Equal(t, "added", added[0].ID)
+	}
+	if assert.Len(t, removed, 1) {
+		assert.Equal(t, "removed", removed[0].ID)
+	}
+	if assert.Len(t, changed, 1) {
+		assert.Equal(t, "changed", changed[0].ID)
+		assert.Equal(t, "exited", changed[0].State)
+	}
+}
+
+func TestDiffAgainstFile_MissingFile(t *testing.T) {
+	mock := newMockDockerClient()
+	mock.setContainers(
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/test1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest"},
+		},
+	)
+
+	path := filepath.Join(t.TempDir(), "missing.json")
+	added, removed, changed, err := DiffAgainstFile(context.Background(), path, WithDockerClient(mock))
+	require.NoError(t, err)
+
+	assert.Len(t, added, 1)
+	assert.Empty(t, removed)
+	assert.Empty(t, changed)
+}
+
+func TestDiffAgainstFile_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, _, _, err := DiffAgainstFile(context.Background(), path, WithDockerClient(newMockDockerClient()))
+	assert.ErrorContains(t, err, "failed to parse snapshot")
+}
+
+func TestSnapshot(t *testing.T) {
+	mock := newMockDockerClient()
+	mock.setContainers(
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container2",
+				Name:  "/web-2",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+		},
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container1",
+				Name:  "/web-1",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "nginx:latest", Labels: map[string]string{"app": "web"}},
+		},
+		container.InspectResponse{
+			ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "container3",
+				Name:  "/db",
+				State: &container.State{Status: "running"},
+			},
+			Config: &container.Config{Image: "postgres:latest", Labels: map[string]string{"app": "db"}},
+		},
+	)
+
+	containers, err := Snapshot(context.Background(), WithDockerClient(mock), WithFilter(LabelEquals("app", "web")))
+	require.NoError(t, err)
+
+	if assert.Len(t, containers, 2) {
+		assert.Equal(t, "container1", containers[0].ID)
+		assert.Equal(t, "container2", containers[1].ID)
+	}
+	assert.Equal(t, 0, mock.eventCallCount(), "should not subscribe to events")
+}
+
+func TestSnapshot_Error(t *testing.T) {
+	mock := newMockDockerClient()
+	mock.listErr = errors.New("daemon unavailable")
+
+	_, err := Snapshot(context.Background(), WithDockerClient(mock))
+	assert.ErrorContains(t, err, "daemon unavailable")
+}
+
+func TestSnapshot_WithListFilters(t *testing.T) {
+	mock := newMockDockerClient()
+	mock.setContainers()
+
+	_, err := Snapshot(context.Background(), WithDockerClient(mock), WithListFilters(filters.NewArgs(filters.Arg("network", "web"))))
+	assert.NoError(t, err)
+
+	if assert.Len(t, mock.listOpts, 1) {
+		assert.True(t, mock.listOpts[0].All)
+		assert.Equal(t, []string{"web"}, mock.listOpts[0].Filters.Get("network"))
+	}
+}
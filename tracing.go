@@ -0,0 +1,38 @@
+package containuum
+
+import "context"
+
+// Tracer records spans describing the work done by a monitor, without this
+// package depending on a particular tracing library. The otelcontainuum
+// package provides an implementation for OpenTelemetry.
+type Tracer interface {
+	// Start starts a span with the given name and attributes. The returned
+	// context carries the span, and is the parent of any spans started with it.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an operation being recorded by a Tracer.
+type Span interface {
+	// SetAttributes adds the given attributes to the span.
+	SetAttributes(attrs ...Attribute)
+
+	// Fail marks the operation as having failed with the given error.
+	Fail(err error)
+
+	// End completes the span.
+	End()
+}
+
+// Attribute is a key-value pair describing a span. The value is either a string
+// or an int.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// noopSpan is a Span that does nothing, used when no tracer is configured.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) Fail(error)                 {}
+func (noopSpan) End()                       {}